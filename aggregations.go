@@ -2,6 +2,7 @@ package godruid
 
 import (
 	"encoding/json"
+	"fmt"
 )
 
 type Aggregation struct {
@@ -20,6 +21,97 @@ type Aggregation struct {
 	UpperLimit  string       `json:"upperLimit,omitempty"`
 	Aggregator  *Aggregation `json:"aggregator,omitempty"`
 	Round       bool         `json:"round,omitempty"`
+
+	// DataSketches extension.
+	Size               int      `json:"size,omitempty"`
+	IsInputThetaSketch bool     `json:"isInputThetaSketch,omitempty"`
+	LgK                int      `json:"lgK,omitempty"`
+	TgtHllType         string   `json:"tgtHllType,omitempty"`
+	K                  int      `json:"k,omitempty"`
+	NominalEntries     int      `json:"nominalEntries,omitempty"`
+	MetricColumns      []string `json:"metricColumns,omitempty"`
+
+	Expression         string `json:"expression,omitempty"`
+	MaxStringBytes     int    `json:"maxStringBytes,omitempty"`
+	IsInputHyperUnique bool   `json:"isInputHyperUnique,omitempty"`
+
+	// Expression aggregator.
+	Fields                []string `json:"fields,omitempty"`
+	AccumulatorIdentifier string   `json:"accumulatorIdentifier,omitempty"`
+	InitialValue          string   `json:"initialValue,omitempty"`
+	InitialCombineValue   string   `json:"initialCombineValue,omitempty"`
+	Fold                  string   `json:"fold,omitempty"`
+	Combine               string   `json:"combine,omitempty"`
+	Compare               string   `json:"compare,omitempty"`
+	Finalize              string   `json:"finalize,omitempty"`
+}
+
+// MarshalJSON refuses to encode an aggregation Druid would reject, see Validate.
+func (a Aggregation) MarshalJSON() ([]byte, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	type alias Aggregation
+	return json.Marshal(alias(a))
+}
+
+// Validate checks that the aggregation has what its type needs. Types this
+// package has no helper for are not checked.
+func (a Aggregation) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("godruid: %s aggregation %q: %s", a.Type, a.Name, fmt.Sprintf(format, args...))
+	}
+	if a.Type == "" {
+		return fmt.Errorf("godruid: aggregation %q has no type", a.Name)
+	}
+	if a.Type == "filtered" {
+		if a.Filter == nil || a.Aggregator == nil {
+			return invalid("needs both a filter and an aggregator")
+		}
+		return a.Aggregator.Validate()
+	}
+	if a.Name == "" {
+		return invalid("has no name")
+	}
+
+	switch a.Type {
+	case "count":
+	case "longSum", "doubleSum", "floatSum",
+		"longMin", "doubleMin", "floatMin", "min",
+		"longMax", "doubleMax", "floatMax", "max":
+		if (a.FieldName == "") == (a.Expression == "") {
+			return invalid("needs exactly one of fieldName and expression")
+		}
+	case "longFirst", "doubleFirst", "floatFirst", "stringFirst",
+		"longLast", "doubleLast", "floatLast", "stringLast",
+		"hyperUnique", "approxHistogramFold",
+		"thetaSketch", "HLLSketchBuild", "HLLSketchMerge",
+		"quantilesDoublesSketch", "arrayOfDoublesSketch":
+		if a.FieldName == "" {
+			return invalid("has no fieldName")
+		}
+	case "cardinality":
+		if len(a.FieldNames) == 0 {
+			return invalid("has no fieldNames")
+		}
+	case "javascript":
+		if len(a.FieldNames) == 0 || a.FnAggregate == "" || a.FnCombine == "" || a.FnReset == "" {
+			return invalid("needs fieldNames, fnAggregate, fnCombine and fnReset")
+		}
+	case "expression":
+		if a.InitialValue == "" || a.Fold == "" {
+			return invalid("needs an initialValue and a fold expression")
+		}
+	}
+	return nil
+}
+
+// WithExpression makes a sum, min or max aggregation read a Druid expression
+// instead of a column, e.g. AggDoubleSum("revenue", "").WithExpression("price * quantity").
+func (a Aggregation) WithExpression(expression string) Aggregation {
+	a.FieldName = ""
+	a.Expression = expression
+	return a
 }
 
 func AggRawJson(rawJson string) Aggregation {
@@ -99,6 +191,121 @@ func AggLongMax(name, fieldName string) Aggregation {
 	}
 }
 
+func AggFloatSum(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "floatSum",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+func AggFloatMin(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "floatMin",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+func AggFloatMax(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "floatMax",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+func AggDoubleFirst(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "doubleFirst",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+func AggDoubleLast(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "doubleLast",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+func AggLongFirst(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "longFirst",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+func AggLongLast(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "longLast",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+func AggFloatFirst(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "floatFirst",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+func AggFloatLast(name, fieldName string) Aggregation {
+	return Aggregation{
+		Type:      "floatLast",
+		Name:      name,
+		FieldName: fieldName,
+	}
+}
+
+// A zero maxStringBytes leaves Druid's default (1024) in place.
+func AggStringFirst(name, fieldName string, maxStringBytes int) Aggregation {
+	return Aggregation{
+		Type:           "stringFirst",
+		Name:           name,
+		FieldName:      fieldName,
+		MaxStringBytes: maxStringBytes,
+	}
+}
+
+func AggStringLast(name, fieldName string, maxStringBytes int) Aggregation {
+	return Aggregation{
+		Type:           "stringLast",
+		Name:           name,
+		FieldName:      fieldName,
+		MaxStringBytes: maxStringBytes,
+	}
+}
+
+func AggHyperUnique(name, fieldName string, isInputHyperUnique, round bool) Aggregation {
+	return Aggregation{
+		Type:               "hyperUnique",
+		Name:               name,
+		FieldName:          fieldName,
+		IsInputHyperUnique: isInputHyperUnique,
+		Round:              round,
+	}
+}
+
+// AggExpression folds the rows with Druid expressions: fold is evaluated for
+// every row with the running value bound to "__acc", starting at initialValue,
+// and combine merges partial results. fields may be nil to let Druid infer them.
+func AggExpression(name string, fields []string, initialValue, fold, combine string) Aggregation {
+	return Aggregation{
+		Type:         "expression",
+		Name:         name,
+		Fields:       fields,
+		InitialValue: initialValue,
+		Fold:         fold,
+		Combine:      combine,
+	}
+}
+
 func AggFiltered(filter *Filter, aggregator *Aggregation) Aggregation {
 	return Aggregation{
 		Type:       "filtered",
@@ -142,3 +349,60 @@ func AggCardinality(name string, fieldNames []string, byRow ...bool) Aggregation
 		ByRow:      isByRow,
 	}
 }
+
+// ---------------------------------
+// DataSketches Aggregations
+// ---------------------------------
+
+// Check http://druid.io/docs/latest/development/extensions-core/datasketches-extension.html for detail description.
+// Zero sizes, lgK and k leave Druid's defaults in place.
+
+func AggThetaSketch(name, fieldName string, isInputThetaSketch bool, size int) Aggregation {
+	return Aggregation{
+		Type:               "thetaSketch",
+		Name:               name,
+		FieldName:          fieldName,
+		IsInputThetaSketch: isInputThetaSketch,
+		Size:               size,
+	}
+}
+
+// tgtHllType is one of "HLL_4", "HLL_6" or "HLL_8".
+func AggHLLSketchBuild(name, fieldName string, lgK int, tgtHllType string) Aggregation {
+	return Aggregation{
+		Type:       "HLLSketchBuild",
+		Name:       name,
+		FieldName:  fieldName,
+		LgK:        lgK,
+		TgtHllType: tgtHllType,
+	}
+}
+
+func AggHLLSketchMerge(name, fieldName string, lgK int, tgtHllType string) Aggregation {
+	return Aggregation{
+		Type:       "HLLSketchMerge",
+		Name:       name,
+		FieldName:  fieldName,
+		LgK:        lgK,
+		TgtHllType: tgtHllType,
+	}
+}
+
+func AggQuantilesDoublesSketch(name, fieldName string, k int) Aggregation {
+	return Aggregation{
+		Type:      "quantilesDoublesSketch",
+		Name:      name,
+		FieldName: fieldName,
+		K:         k,
+	}
+}
+
+func AggArrayOfDoublesSketch(name, fieldName string, nominalEntries int, metricColumns []string) Aggregation {
+	return Aggregation{
+		Type:           "arrayOfDoublesSketch",
+		Name:           name,
+		FieldName:      fieldName,
+		NominalEntries: nominalEntries,
+		MetricColumns:  metricColumns,
+	}
+}
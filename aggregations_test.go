@@ -0,0 +1,59 @@
+package godruid
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAggregations(t *testing.T) {
+	Convey("TestAggregations", t, func() {
+		cases := []struct {
+			agg  Aggregation
+			json string
+		}{
+			{AggFloatSum("price", "price"), `{"type":"floatSum","name":"price","fieldName":"price"}`},
+			{AggDoubleSum("revenue", "").WithExpression("price * quantity"), `{"type":"doubleSum","name":"revenue","expression":"price * quantity"}`},
+			{AggLongFirst("first", "added"), `{"type":"longFirst","name":"first","fieldName":"added"}`},
+			{AggFloatLast("last", "price"), `{"type":"floatLast","name":"last","fieldName":"price"}`},
+			{AggStringFirst("page", "page", 1024), `{"type":"stringFirst","name":"page","fieldName":"page","maxStringBytes":1024}`},
+			{AggStringLast("page", "page", 0), `{"type":"stringLast","name":"page","fieldName":"page"}`},
+			{AggHyperUnique("users", "userHll", true, true), `{"type":"hyperUnique","name":"users","fieldName":"userHll","round":true,"isInputHyperUnique":true}`},
+			{AggExpression("total", []string{"added"}, "0", "__acc + added", "__acc + total"),
+				`{"type":"expression","name":"total","fields":["added"],"initialValue":"0","fold":"__acc + added","combine":"__acc + total"}`},
+			{AggThetaSketch("users", "user", false, 16384), `{"type":"thetaSketch","name":"users","fieldName":"user","size":16384}`},
+			{AggThetaSketch("users", "userSketch", true, 0), `{"type":"thetaSketch","name":"users","fieldName":"userSketch","isInputThetaSketch":true}`},
+			{AggHLLSketchBuild("users", "user", 12, "HLL_4"), `{"type":"HLLSketchBuild","name":"users","fieldName":"user","lgK":12,"tgtHllType":"HLL_4"}`},
+			{AggHLLSketchMerge("users", "userSketch", 0, ""), `{"type":"HLLSketchMerge","name":"users","fieldName":"userSketch"}`},
+			{AggQuantilesDoublesSketch("latency", "latency", 128), `{"type":"quantilesDoublesSketch","name":"latency","fieldName":"latency","k":128}`},
+			{AggArrayOfDoublesSketch("sales", "user", 4096, []string{"price", "quantity"}),
+				`{"type":"arrayOfDoublesSketch","name":"sales","fieldName":"user","nominalEntries":4096,"metricColumns":["price","quantity"]}`},
+		}
+		for _, c := range cases {
+			data, err := json.Marshal(c.agg)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, c.json)
+		}
+
+		Convey("invalid aggregations", func() {
+			for _, agg := range []Aggregation{
+				{Name: "untyped"},
+				AggCount(""),
+				AggLongSum("sum", ""),
+				{Type: "longSum", Name: "sum", FieldName: "added", Expression: "added * 2"},
+				AggDoubleFirst("first", ""),
+				AggCardinality("pages", nil),
+				AggJavaScript("js", "", "", "", []string{"added"}),
+				AggExpression("total", nil, "", "__acc + added", ""),
+				AggExpression("total", nil, "0", "", ""),
+				AggFiltered(nil, &Aggregation{Type: "count", Name: "rows"}),
+				AggFiltered(FilterSelector("country", "US"), &Aggregation{Type: "longSum", Name: "sum"}),
+			} {
+				So(agg.Validate(), ShouldNotBeNil)
+				_, err := json.Marshal(agg)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
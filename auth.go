@@ -0,0 +1,91 @@
+package godruid
+
+import (
+	"net/http"
+)
+
+// Authenticator adds credentials to every request the Client sends.
+// Mutual TLS is configured on the transport of Client.HttpClient instead.
+type Authenticator interface {
+	Authenticate(request *http.Request) error
+}
+
+// AuthenticatorFunc lets an ordinary function be used as an Authenticator.
+type AuthenticatorFunc func(request *http.Request) error
+
+func (f AuthenticatorFunc) Authenticate(request *http.Request) error {
+	return f(request)
+}
+
+// BasicAuth is Druid's basic security extension.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a *BasicAuth) Authenticate(request *http.Request) error {
+	request.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerTokenAuth sends "Authorization: Bearer <token>".
+type BearerTokenAuth struct {
+	Token string
+}
+
+func (a *BearerTokenAuth) Authenticate(request *http.Request) error {
+	request.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// CookieAuth sends the token as a cookie.
+type CookieAuth struct {
+	Name  string
+	Value string
+}
+
+func (a *CookieAuth) Authenticate(request *http.Request) error {
+	request.AddCookie(&http.Cookie{
+		Name:  a.Name,
+		Value: a.Value,
+	})
+	return nil
+}
+
+// HeaderAuth sends the token in an arbitrary header.
+type HeaderAuth struct {
+	Name  string
+	Value string
+}
+
+func (a *HeaderAuth) Authenticate(request *http.Request) error {
+	request.Header.Set(a.Name, a.Value)
+	return nil
+}
+
+func AuthBasic(username, password string) Authenticator {
+	return &BasicAuth{
+		Username: username,
+		Password: password,
+	}
+}
+
+func AuthBearerToken(token string) Authenticator {
+	return &BearerTokenAuth{
+		Token: token,
+	}
+}
+
+func AuthCookie(name, value string) Authenticator {
+	return &CookieAuth{
+		Name:  name,
+		Value: value,
+	}
+}
+
+func AuthHeader(name, value string) Authenticator {
+	return &HeaderAuth{
+		Name:  name,
+		Value: value,
+	}
+}
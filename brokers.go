@@ -0,0 +1,251 @@
+package godruid
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHealthEndPoint = "/status/health"
+
+	defaultMaxFailures = 3
+	defaultCooldown    = 30 * time.Second
+)
+
+var ErrNoBrokers = errors.New("godruid: broker pool is empty")
+
+type BalanceStrategy int
+
+const (
+	// RoundRobin hands out the healthy brokers in turn.
+	RoundRobin BalanceStrategy = iota
+	// LeastOutstanding picks the healthy broker with the fewest requests in flight.
+	LeastOutstanding
+)
+
+// BrokerPool spreads a Client's requests over several brokers.
+//
+// Brokers are taken out of rotation after MaxFailures consecutive failed
+// requests (see IsRetryable for what counts as a failure), or when a health
+// probe fails. A broker comes back once a health probe succeeds, or on its own
+// after Cooldown: a single request is then let through to it, and it's back if
+// that request succeeds, or out for another Cooldown if it fails.
+// If every broker is out of rotation, all of them are tried anyway.
+type BrokerPool struct {
+	Strategy    BalanceStrategy
+	MaxFailures int
+	Cooldown    time.Duration
+
+	mu      sync.Mutex
+	brokers []*broker
+	next    int
+	stop    chan struct{}
+}
+
+type broker struct {
+	url         string
+	outstanding int
+	failures    int
+	down        bool
+	downSince   time.Time
+	// probing is set while the single request let through after Cooldown runs.
+	probing bool
+}
+
+func NewBrokerPool(urls []string, strategy BalanceStrategy) *BrokerPool {
+	p := &BrokerPool{
+		Strategy:    strategy,
+		MaxFailures: defaultMaxFailures,
+		Cooldown:    defaultCooldown,
+	}
+	for _, url := range urls {
+		p.brokers = append(p.brokers, &broker{url: strings.TrimRight(url, "/")})
+	}
+	return p
+}
+
+// Urls returns every broker of the pool.
+func (p *BrokerPool) Urls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	urls := make([]string, len(p.brokers))
+	for i, b := range p.brokers {
+		urls[i] = b.url
+	}
+	return urls
+}
+
+// Healthy returns the brokers currently in rotation.
+func (p *BrokerPool) Healthy() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var urls []string
+	for _, b := range p.brokers {
+		if !b.down {
+			urls = append(urls, b.url)
+		}
+	}
+	return urls
+}
+
+// pick chooses the broker for the next request. avoid is the broker the
+// previous attempt failed on; it's skipped if there is anything else to try.
+func (p *BrokerPool) pick(avoid *broker) (*broker, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.brokers) == 0 {
+		return nil, ErrNoBrokers
+	}
+
+	now := time.Now()
+	var candidates []*broker
+	for _, b := range p.brokers {
+		if b != avoid && (!b.down || p.probeDue(b, now)) {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.brokers
+	}
+
+	var chosen *broker
+	switch p.Strategy {
+	case LeastOutstanding:
+		for _, b := range candidates {
+			if chosen == nil || b.outstanding < chosen.outstanding {
+				chosen = b
+			}
+		}
+	default:
+		chosen = candidates[p.next%len(candidates)]
+		p.next++
+	}
+	if p.probeDue(chosen, now) {
+		chosen.probing = true
+	}
+	chosen.outstanding++
+	return chosen, nil
+}
+
+// probeDue tells whether b is down and may be sent a request to prove itself.
+func (p *BrokerPool) probeDue(b *broker, now time.Time) bool {
+	return b.down && !b.probing && now.Sub(b.downSince) >= p.Cooldown
+}
+
+// done records the outcome of a request sent to b.
+func (p *BrokerPool) done(b *broker, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b.outstanding--
+	failed := err != nil && IsRetryable(err)
+	if b.probing {
+		b.probing = false
+		if b.down {
+			if failed {
+				b.downSince = time.Now()
+			} else {
+				b.down = false
+				b.failures = 0
+			}
+			return
+		}
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= p.MaxFailures && !b.down {
+		b.down = true
+		b.downSince = time.Now()
+	}
+}
+
+func (p *BrokerPool) setHealth(url string, healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, b := range p.brokers {
+		if b.url != url {
+			continue
+		}
+		if healthy {
+			b.down = false
+			b.failures = 0
+		} else if !b.down {
+			b.down = true
+			b.downSince = time.Now()
+		}
+	}
+}
+
+// CheckHealth probes GET /status/health on every broker and updates the rotation.
+func (p *BrokerPool) CheckHealth(ctx context.Context, client *http.Client) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var wg sync.WaitGroup
+	for _, url := range p.Urls() {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			p.setHealth(url, probeHealth(ctx, client, url))
+		}(url)
+	}
+	wg.Wait()
+}
+
+// StartHealthChecks runs CheckHealth every interval until Stop is called.
+func (p *BrokerPool) StartHealthChecks(client *http.Client, interval time.Duration) {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	p.stop = stop
+	p.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				p.CheckHealth(ctx, client)
+				cancel()
+			}
+		}
+	}()
+}
+
+// Stop ends the health checks started by StartHealthChecks.
+func (p *BrokerPool) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func probeHealth(ctx context.Context, client *http.Client, url string) bool {
+	request, err := http.NewRequest("GET", url+DefaultHealthEndPoint, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return err == nil && resp.StatusCode == http.StatusOK && strings.TrimSpace(string(body)) == "true"
+}
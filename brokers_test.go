@@ -0,0 +1,134 @@
+package godruid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBrokerPool(t *testing.T) {
+	Convey("TestBrokerPool", t, func() {
+		unavailable := &DruidError{StatusCode: http.StatusServiceUnavailable}
+
+		// newBroker starts a broker answering queries with status, and health
+		// probes with healthy.
+		newBroker := func(status int, healthy bool) (*httptest.Server, *int32) {
+			hits := new(int32)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == DefaultHealthEndPoint {
+					fmt.Fprint(w, healthy)
+					return
+				}
+				atomic.AddInt32(hits, 1)
+				w.WriteHeader(status)
+				fmt.Fprint(w, `[]`)
+			}))
+			return server, hits
+		}
+		query := &QueryTimeseries{
+			DataSource:   "campaign",
+			Intervals:    "2024-01-01/2024-01-02",
+			Granularity:  GranAll,
+			Aggregations: []Aggregation{AggCount("count")},
+		}
+
+		Convey("round robin", func() {
+			a, aHits := newBroker(http.StatusOK, true)
+			defer a.Close()
+			b, bHits := newBroker(http.StatusOK, true)
+			defer b.Close()
+
+			client := &Client{Brokers: NewBrokerPool([]string{a.URL, b.URL + "/"}, RoundRobin)}
+			for i := 0; i < 4; i++ {
+				So(client.Query(query), ShouldBeNil)
+			}
+			So(atomic.LoadInt32(aHits), ShouldEqual, 2)
+			So(atomic.LoadInt32(bHits), ShouldEqual, 2)
+		})
+
+		Convey("fails over to another broker", func() {
+			a, aHits := newBroker(http.StatusServiceUnavailable, true)
+			defer a.Close()
+			b, bHits := newBroker(http.StatusOK, true)
+			defer b.Close()
+
+			client := &Client{
+				Brokers: NewBrokerPool([]string{a.URL, b.URL}, RoundRobin),
+				Retry:   &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+			}
+			So(client.Query(query), ShouldBeNil)
+			So(atomic.LoadInt32(aHits), ShouldEqual, 1)
+			So(atomic.LoadInt32(bHits), ShouldEqual, 1)
+		})
+
+		Convey("least outstanding", func() {
+			p := NewBrokerPool([]string{"http://a", "http://b"}, LeastOutstanding)
+			a, _ := p.pick(nil)
+			b, _ := p.pick(nil)
+			So([]string{a.url, b.url}, ShouldResemble, []string{"http://a", "http://b"})
+			p.done(a, nil)
+			next, _ := p.pick(nil)
+			So(next, ShouldEqual, a)
+		})
+
+		Convey("takes failing brokers down and lets them prove themselves after the cooldown", func() {
+			p := NewBrokerPool([]string{"http://a", "http://b"}, RoundRobin)
+			p.MaxFailures = 2
+			p.Cooldown = 50 * time.Millisecond
+			a := p.brokers[0]
+
+			p.done(a, unavailable)
+			So(p.Healthy(), ShouldResemble, []string{"http://a", "http://b"})
+			p.done(a, unavailable)
+			So(p.Healthy(), ShouldResemble, []string{"http://b"})
+			for i := 0; i < 3; i++ {
+				b, _ := p.pick(nil)
+				So(b.url, ShouldEqual, "http://b")
+				p.done(b, nil)
+			}
+
+			// A single request goes through once the cooldown is over.
+			time.Sleep(60 * time.Millisecond)
+			picked := map[string]int{}
+			for i := 0; i < 4; i++ {
+				b, _ := p.pick(nil)
+				picked[b.url]++
+			}
+			So(picked, ShouldResemble, map[string]int{"http://a": 1, "http://b": 3})
+			So(p.Healthy(), ShouldResemble, []string{"http://b"})
+
+			Convey("back on success", func() {
+				p.done(a, nil)
+				So(p.Healthy(), ShouldResemble, []string{"http://a", "http://b"})
+			})
+
+			Convey("out for another cooldown on failure", func() {
+				p.done(a, unavailable)
+				So(p.Healthy(), ShouldResemble, []string{"http://b"})
+				b, _ := p.pick(nil)
+				So(b.url, ShouldEqual, "http://b")
+			})
+		})
+
+		Convey("health probes", func() {
+			a, _ := newBroker(http.StatusOK, false)
+			defer a.Close()
+			b, _ := newBroker(http.StatusOK, true)
+			defer b.Close()
+
+			p := NewBrokerPool([]string{a.URL, b.URL}, RoundRobin)
+			p.CheckHealth(context.Background(), nil)
+			So(p.Healthy(), ShouldResemble, []string{b.URL})
+
+			a.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "true") })
+			p.CheckHealth(context.Background(), nil)
+			So(p.Healthy(), ShouldResemble, []string{a.URL, b.URL})
+		})
+	})
+}
@@ -2,39 +2,64 @@ package godruid
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync"
+	"time"
 )
 
 const (
-	DefaultEndPoint = "/druid/v2"
+	DefaultEndPoint    = "/druid/v2"
+	DefaultSQLEndPoint = "/druid/v2/sql"
+
+	// How long we wait for the broker to accept a cancellation request.
+	cancelTimeout = 5 * time.Second
 )
 
 type Client struct {
-	Url      string
-	EndPoint string
+	Url         string
+	EndPoint    string
+	SQLEndPoint string
 
 	Debug        bool
 	LastRequest  string
 	LastResponse string
 	HttpClient   *http.Client
+
+	// Auth, if set, adds credentials to every request.
+	Auth Authenticator
+	// Retry, if set, retries requests that failed for transient reasons.
+	Retry *RetryPolicy
+	// Brokers, if set, is used instead of Url to spread requests over several brokers.
+	// Retries go to a different broker than the one that just failed.
+	Brokers *BrokerPool
 }
 
-func (c *Client) Query(query Query, authToken string) (err error) {
-	query.setup()
-	var reqJson []byte
-	if c.Debug {
-		reqJson, err = json.MarshalIndent(query, "", "  ")
-	} else {
-		reqJson, err = json.Marshal(query)
-	}
+func (c *Client) Query(query Query) (err error) {
+	return c.QueryContext(context.Background(), query)
+}
+
+// QueryContext is like Query, but the request is bound to ctx.
+// If ctx is cancelled or its deadline expires before the result comes back,
+// the query is also cancelled on the broker.
+func (c *Client) QueryContext(ctx context.Context, query Query) (err error) {
+	reqJson, err := c.marshalQuery(query)
 	if err != nil {
 		return
 	}
 
-	result, err := c.QueryRaw(reqJson, authToken)
+	var result []byte
+	if _, ok := query.(*QuerySQL); ok {
+		if c.SQLEndPoint == "" {
+			c.SQLEndPoint = DefaultSQLEndPoint
+		}
+		result, err = c.queryRaw(ctx, c.SQLEndPoint, reqJson)
+	} else {
+		result, err = c.QueryRawContext(ctx, reqJson)
+	}
 	if err != nil {
 		return
 	}
@@ -42,52 +67,220 @@ func (c *Client) Query(query Query, authToken string) (err error) {
 	return query.onResponse(result)
 }
 
-func (c *Client) QueryRaw(req []byte, authToken string) (result []byte, err error) {
+func (c *Client) QueryRaw(req []byte) (result []byte, err error) {
+	return c.QueryRawContext(context.Background(), req)
+}
+
+// QueryRawContext is like QueryRaw, but the request is bound to ctx.
+// When ctx is done before the response is read and req carries a
+// context.queryId, a DELETE for that query is sent to the broker.
+func (c *Client) QueryRawContext(ctx context.Context, req []byte) (result []byte, err error) {
 	if c.EndPoint == "" {
 		c.EndPoint = DefaultEndPoint
 	}
-	endPoint := c.EndPoint
+	return c.queryRaw(ctx, c.EndPoint, req)
+}
+
+func (c *Client) queryRaw(ctx context.Context, queryEndPoint string, req []byte) (result []byte, err error) {
+	_, release, err := c.send(ctx, queryEndPoint, req, func(resp *http.Response) error {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if c.Debug {
+			c.LastResponse = string(body)
+		}
+		result = body
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	release(nil)
+	return
+}
+
+// marshalQuery readies query for sending. A query without a queryId in its
+// Context is sent with a new one, that is not kept there: each request gets
+// its own, so that cancelling one never stops another. The id sent is left in
+// the query's QueryID, for CancelQuery or the broker logs.
+func (c *Client) marshalQuery(query Query) ([]byte, error) {
+	query.setup()
+	defer query.setQueryID()()
+	if c.Debug {
+		return json.MarshalIndent(query, "", "  ")
+	}
+	return json.Marshal(query)
+}
+
+// send posts req to the query end point, retrying and failing over to other
+// brokers as configured. The 200 response of an attempt is passed to handle,
+// whose error is taken as the outcome of that attempt. It returns the url of
+// the broker that was tried last. On success, the request counts as
+// outstanding on that broker until release is called with the outcome of
+// reading the response, which handle may have left to the caller.
+func (c *Client) send(ctx context.Context, queryEndPoint string, req []byte, handle func(resp *http.Response) error) (url string, release func(error), err error) {
+	endPoint := queryEndPoint
 	if c.Debug {
 		endPoint += "?pretty"
 		c.LastRequest = string(req)
 	}
-	if err != nil {
-		return
+
+	// The query can only be cancelled on the broker that is running it.
+	url = c.Url
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			if queryID := rawQueryID(req); queryID != "" {
+				c.cancelQuery(url, queryEndPoint, queryID)
+			}
+		}
+	}()
+
+	var b *broker
+	for attempt := 1; ; attempt++ {
+		if c.Brokers != nil {
+			if b, err = c.Brokers.pick(b); err != nil {
+				return
+			}
+			url = b.url
+		}
+		err = c.post(ctx, url+endPoint, req, handle)
+		if err == nil {
+			return url, c.release(b), nil
+		}
+		if b != nil {
+			c.Brokers.done(b, err)
+		}
+		if ctx.Err() != nil || !c.Retry.retry(attempt, err) {
+			return
+		}
+		if werr := c.Retry.wait(ctx, attempt); werr != nil {
+			return url, nil, werr
+		}
 	}
+}
 
-	request, err := http.NewRequest("POST", c.Url+endPoint, bytes.NewBuffer(req))
+// release returns the func that ends a successful request to b, b being nil
+// without a BrokerPool.
+func (c *Client) release(b *broker) func(error) {
+	var once sync.Once
+	return func(err error) {
+		if b != nil {
+			once.Do(func() { c.Brokers.done(b, err) })
+		}
+	}
+}
+
+// post makes a single attempt at sending req to url.
+func (c *Client) post(ctx context.Context, url string, req []byte, handle func(resp *http.Response) error) error {
+	request, err := http.NewRequest("POST", url, bytes.NewBuffer(req))
 	if err != nil {
-		return nil, err
+		return err
 	}
+	request = request.WithContext(ctx)
 	request.Header.Set("Content-Type", "application/json")
-	if authToken != "" {
-		cookie := &http.Cookie{
-			Name:  "skylight-aaa",
-			Value: authToken,
+	if err = c.authenticate(request); err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient().Do(request)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if c.Debug {
+			c.LastResponse = string(body)
 		}
-		request.AddCookie(cookie)
+		return newDruidError(resp, body)
 	}
 
-	resp, err := c.HttpClient.Do(request)
-	defer func() {
-		resp.Body.Close()
-	}()
+	return handle(resp)
+}
+
+// CancelQuery asks the broker to stop running the query with the given queryId.
+// With a BrokerPool the request goes to every broker, since only the one
+// running the query knows about it.
+func (c *Client) CancelQuery(queryID string) error {
+	if c.EndPoint == "" {
+		c.EndPoint = DefaultEndPoint
+	}
+	if c.Brokers == nil {
+		return c.cancelQuery(c.Url, c.EndPoint, queryID)
+	}
+	var err error
+	cancelled := false
+	for _, url := range c.Brokers.Urls() {
+		if cerr := c.cancelQuery(url, c.EndPoint, queryID); cerr != nil {
+			err = cerr
+		} else {
+			cancelled = true
+		}
+	}
+	if cancelled {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) cancelQuery(url, endPoint, queryID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
 
+	request, err := http.NewRequest("DELETE", url+endPoint+"/"+queryID, nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	request = request.WithContext(ctx)
+	if err = c.authenticate(request); err != nil {
+		return err
 	}
 
-	result, err = ioutil.ReadAll(resp.Body)
+	resp, err := c.httpClient().Do(request)
 	if err != nil {
-		return
+		return err
 	}
-	if c.Debug {
-		c.LastResponse = string(result)
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
 	}
+	return fmt.Errorf("cancel query %s: %s", queryID, resp.Status)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s: %s", resp.Status, string(result))
+func (c *Client) httpClient() *http.Client {
+	if c.HttpClient == nil {
+		return http.DefaultClient
 	}
+	return c.HttpClient
+}
 
-	return
+func (c *Client) authenticate(request *http.Request) error {
+	if c.Auth == nil {
+		return nil
+	}
+	return c.Auth.Authenticate(request)
+}
+
+// rawQueryID pulls context.queryId (or context.sqlQueryId for SQL) out of an
+// already marshalled query.
+func rawQueryID(req []byte) string {
+	var q struct {
+		Context map[string]interface{} `json:"context"`
+	}
+	if json.Unmarshal(req, &q) != nil {
+		return ""
+	}
+	if id := queryID(q.Context); id != "" {
+		return id
+	}
+	return sqlQueryID(q.Context)
 }
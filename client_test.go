@@ -1,9 +1,15 @@
 package godruid
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestGroupby(t *testing.T) {
@@ -62,3 +68,104 @@ func TestSearch(t *testing.T) {
 
 	})
 }
+
+func TestRetry(t *testing.T) {
+	Convey("TestRetry", t, func() {
+		var attempts int32
+		var failures []int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := int(atomic.AddInt32(&attempts, 1))
+			if n <= len(failures) {
+				w.WriteHeader(failures[n-1])
+				fmt.Fprint(w, `{"error":"Unknown exception","errorMessage":"oops"}`)
+				return
+			}
+			fmt.Fprint(w, `[]`)
+		}))
+		defer server.Close()
+
+		client := Client{
+			Url: server.URL,
+			Retry: &RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+			},
+		}
+		query := &QueryTimeseries{
+			DataSource:   "campaign",
+			Intervals:    []string{"2014-09-01T00:00/2020-01-01T00"},
+			Granularity:  GranAll,
+			Aggregations: []Aggregation{AggCount("count")},
+		}
+
+		Convey("transient failures are retried", func() {
+			failures = []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+			So(client.Query(query), ShouldBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 3)
+		})
+
+		Convey("gives up after MaxAttempts", func() {
+			failures = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+			err := client.Query(query)
+			So(err, ShouldNotBeNil)
+			So(err.(*DruidError).StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 3)
+		})
+
+		Convey("query errors are not retried", func() {
+			failures = []int{http.StatusBadRequest}
+			So(client.Query(query), ShouldNotBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestCancel(t *testing.T) {
+	Convey("TestCancel", t, func() {
+		posted := make(chan string, 2)
+		deleted := make(chan string, 2)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "DELETE" {
+				deleted <- r.URL.Path
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			var q struct {
+				Context map[string]interface{} `json:"context"`
+			}
+			json.NewDecoder(r.Body).Decode(&q)
+			posted <- queryID(q.Context)
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		client := Client{Url: server.URL}
+		query := &QueryTimeseries{
+			DataSource:   "campaign",
+			Intervals:    "2024-01-01/2024-01-02",
+			Granularity:  GranAll,
+			Aggregations: []Aggregation{AggCount("count")},
+		}
+		run := func() string {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- client.QueryContext(ctx, query) }()
+			id := <-posted
+			cancel()
+			So(<-done, ShouldNotBeNil)
+			So(<-deleted, ShouldEqual, "/druid/v2/"+id)
+			return id
+		}
+
+		first, second := run(), run()
+		So(first, ShouldNotBeEmpty)
+		So(second, ShouldNotEqual, first)
+		So(query.Context, ShouldBeNil)
+		So(query.QueryID, ShouldEqual, second)
+		So(query.GetQueryID(), ShouldEqual, second)
+
+		query.Context = map[string]interface{}{"queryId": "mine"}
+		So(run(), ShouldEqual, "mine")
+		So(query.QueryID, ShouldEqual, "mine")
+	})
+}
@@ -0,0 +1,118 @@
+package godruid
+
+import (
+	"encoding/json"
+)
+
+// DataSource is what a query reads from. A plain string or a TableName is the
+// name of a table; the other kinds are built with the DataSourceXxx functions.
+// Check http://druid.io/docs/latest/querying/datasource.html for detail description.
+type DataSource interface{}
+
+// TableName is a table datasource given by its name alone. It goes to Druid
+// as a plain string.
+type TableName string
+
+type TableDataSource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// QueryDataSource reads from the result of another query, e.g. a nested groupBy.
+type QueryDataSource struct {
+	Type  string `json:"type"`
+	Query Query  `json:"query"`
+}
+
+type UnionDataSource struct {
+	Type        string   `json:"type"`
+	DataSources []string `json:"dataSources"`
+}
+
+type InlineDataSource struct {
+	Type        string          `json:"type"`
+	ColumnNames []string        `json:"columnNames"`
+	ColumnTypes []string        `json:"columnTypes,omitempty"`
+	Rows        [][]interface{} `json:"rows"`
+}
+
+type LookupDataSource struct {
+	Type   string `json:"type"`
+	Lookup string `json:"lookup"`
+}
+
+type JoinType string
+
+const (
+	JoinInner JoinType = "INNER"
+	JoinLeft  JoinType = "LEFT"
+)
+
+// JoinDataSource joins Left with Right. Condition is a Druid expression in
+// which the columns of Right carry RightPrefix, e.g. `country == "r.code"`.
+type JoinDataSource struct {
+	Type        string     `json:"type"`
+	Left        DataSource `json:"left"`
+	Right       DataSource `json:"right"`
+	RightPrefix string     `json:"rightPrefix"`
+	Condition   string     `json:"condition"`
+	JoinType    JoinType   `json:"joinType"`
+}
+
+// The inner query is sent without going through the Client, so its queryType
+// has to be filled in here.
+func (ds QueryDataSource) MarshalJSON() ([]byte, error) {
+	if ds.Query != nil {
+		ds.Query.setup()
+	}
+	type alias QueryDataSource
+	return json.Marshal(alias(ds))
+}
+
+func DataSourceTable(name string) *TableDataSource {
+	return &TableDataSource{
+		Type: "table",
+		Name: name,
+	}
+}
+
+func DataSourceQuery(query Query) *QueryDataSource {
+	return &QueryDataSource{
+		Type:  "query",
+		Query: query,
+	}
+}
+
+func DataSourceUnion(dataSources ...string) *UnionDataSource {
+	return &UnionDataSource{
+		Type:        "union",
+		DataSources: dataSources,
+	}
+}
+
+func DataSourceInline(columnNames, columnTypes []string, rows [][]interface{}) *InlineDataSource {
+	return &InlineDataSource{
+		Type:        "inline",
+		ColumnNames: columnNames,
+		ColumnTypes: columnTypes,
+		Rows:        rows,
+	}
+}
+
+func DataSourceLookup(lookup string) *LookupDataSource {
+	return &LookupDataSource{
+		Type:   "lookup",
+		Lookup: lookup,
+	}
+}
+
+func DataSourceJoin(left, right DataSource, rightPrefix, condition string, joinType JoinType) *JoinDataSource {
+	return &JoinDataSource{
+		Type:        "join",
+		Left:        left,
+		Right:       right,
+		RightPrefix: rightPrefix,
+		Condition:   condition,
+		JoinType:    joinType,
+	}
+}
@@ -0,0 +1,56 @@
+package godruid
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDataSources(t *testing.T) {
+	Convey("TestDataSources", t, func() {
+		inner := &QueryGroupBy{
+			DataSource:   "wikipedia",
+			Granularity:  GranAll,
+			Dimensions:   []DimSpec{"page"},
+			Aggregations: []Aggregation{AggCount("rows")},
+			Intervals:    "2024-01-01/2024-01-02",
+		}
+		cases := []struct {
+			dataSource DataSource
+			json       string
+		}{
+			{"wikipedia", `"wikipedia"`},
+			{TableName("wikipedia"), `"wikipedia"`},
+			{DataSourceTable("wikipedia"), `{"type":"table","name":"wikipedia"}`},
+			{DataSourceUnion("a", "b"), `{"type":"union","dataSources":["a","b"]}`},
+			{DataSourceInline([]string{"code", "name"}, []string{"STRING", "STRING"}, [][]interface{}{{"US", "United States"}}),
+				`{"type":"inline","columnNames":["code","name"],"columnTypes":["STRING","STRING"],"rows":[["US","United States"]]}`},
+			{DataSourceLookup("countries"), `{"type":"lookup","lookup":"countries"}`},
+			{DataSourceJoin(TableName("wikipedia"), DataSourceLookup("countries"), "r.", `country == "r.k"`, JoinLeft),
+				`{"type":"join","left":"wikipedia","right":{"type":"lookup","lookup":"countries"},"rightPrefix":"r.","condition":"country == \"r.k\"","joinType":"LEFT"}`},
+		}
+		for _, c := range cases {
+			data, err := json.Marshal(c.dataSource)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, c.json)
+		}
+
+		// The inner query gets its queryType, whether held by pointer or by value.
+		for ds, queryType := range map[DataSource]QueryType{
+			DataSourceQuery(inner):              GROUPBY,
+			*DataSourceQuery(&QueryTopN{}):      TOPN,
+			DataSourceQuery(&QueryTimeseries{}): TIMESERIES,
+		} {
+			data, err := json.Marshal(ds)
+			So(err, ShouldBeNil)
+			var sent struct {
+				Type  string
+				Query struct{ QueryType QueryType }
+			}
+			So(json.Unmarshal(data, &sent), ShouldBeNil)
+			So(sent.Type, ShouldEqual, "query")
+			So(sent.Query.QueryType, ShouldEqual, queryType)
+		}
+	})
+}
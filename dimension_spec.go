@@ -1,33 +1,93 @@
 package godruid
 
+import (
+	"encoding/json"
+)
+
 type DimSpec interface{}
 
 type Dimension struct {
-	Type         string           `json:"type"`
-	Dimension    string           `json:"dimension"`
-	OutputName   string           `json:"outputName"`
-	ExtractionFn *DimExtractionFn `json:"extractionFn,omitempty"`
-}
-
-type DimExtractionFn struct {
-	Type        string       `json:"type"`
-	Expr        string       `json:"expr,omitempty"`
-	Query       *SearchQuery `json:"query,omitempty"`
-	Format      string       `json:"format,omitempty"`
-	Function    string       `json:"function,omitempty"`
-	TimeZone    string       `json:"timeZone,omitempty"`
-	Locale      string       `json:"locale,omitempty"`
-	Granularity string       `json:"granularity,omitempty"`
-	AsMillis    bool         `json:"asMillis,omitempty"`
+	Type         string       `json:"type"`
+	Dimension    string       `json:"dimension"`
+	OutputName   string       `json:"outputName"`
+	OutputType   OutputType   `json:"outputType,omitempty"`
+	ExtractionFn ExtractionFn `json:"extractionFn,omitempty"`
 }
 
 type TimeExtractionDimensionSpec struct {
 	Type               string       `json:"type"`
 	Dimension          string       `json:"dimension"`
 	OutputName         string       `json:"outputName"`
+	OutputType         OutputType   `json:"outputType,omitempty"`
 	ExtractionFunction ExtractionFn `json:"extractionFn"`
 }
 
+// LookupDimension maps values either through the lookup registered on the
+// cluster under Name, or through the inline Lookup.
+type LookupDimension struct {
+	Type                    string     `json:"type"`
+	Dimension               string     `json:"dimension"`
+	OutputName              string     `json:"outputName"`
+	OutputType              OutputType `json:"outputType,omitempty"`
+	Name                    string     `json:"name,omitempty"`
+	Lookup                  *MapLookup `json:"lookup,omitempty"`
+	RetainMissingValue      bool       `json:"retainMissingValue,omitempty"`
+	ReplaceMissingValueWith string     `json:"replaceMissingValueWith,omitempty"`
+	Optimize                *bool      `json:"optimize,omitempty"`
+}
+
+// The filtered dimension specs below only keep some of the values of a
+// multi-value dimension. Their name and output type are those of the Delegate.
+
+type ListFilteredDimension struct {
+	Type        string   `json:"type"`
+	Delegate    DimSpec  `json:"delegate"`
+	Values      []string `json:"values"`
+	IsWhitelist *bool    `json:"isWhitelist,omitempty"`
+}
+
+type RegexFilteredDimension struct {
+	Type     string  `json:"type"`
+	Delegate DimSpec `json:"delegate"`
+	Pattern  string  `json:"pattern"`
+}
+
+type PrefixFilteredDimension struct {
+	Type     string  `json:"type"`
+	Delegate DimSpec `json:"delegate"`
+	Prefix   string  `json:"prefix"`
+}
+
+func (d *Dimension) UnmarshalJSON(data []byte) error {
+	type alias Dimension
+	var raw struct {
+		*alias
+		ExtractionFn json.RawMessage `json:"extractionFn"`
+	}
+	raw.alias = (*alias)(d)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	fn, err := UnmarshalExtractionFn(raw.ExtractionFn)
+	d.ExtractionFn = fn
+	return err
+}
+
+func (d *TimeExtractionDimensionSpec) UnmarshalJSON(data []byte) error {
+	type alias TimeExtractionDimensionSpec
+	var raw struct {
+		*alias
+		ExtractionFn json.RawMessage `json:"extractionFn"`
+	}
+	raw.alias = (*alias)(d)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	fn, err := UnmarshalExtractionFn(raw.ExtractionFn)
+	d.ExtractionFunction = fn
+	return err
+}
+
 func DimDefault(dimension, outputName string) DimSpec {
 	return &Dimension{
 		Type:       "default",
@@ -36,7 +96,16 @@ func DimDefault(dimension, outputName string) DimSpec {
 	}
 }
 
-func DimExtraction(dimension, outputName string, fn *DimExtractionFn) DimSpec {
+func DimDefaultTyped(dimension, outputName string, outputType OutputType) DimSpec {
+	return &Dimension{
+		Type:       "default",
+		Dimension:  dimension,
+		OutputName: outputName,
+		OutputType: outputType,
+	}
+}
+
+func DimExtraction(dimension, outputName string, fn ExtractionFn) DimSpec {
 	return &Dimension{
 		Type:         "extraction",
 		Dimension:    dimension,
@@ -45,41 +114,125 @@ func DimExtraction(dimension, outputName string, fn *DimExtractionFn) DimSpec {
 	}
 }
 
-func DimExFnRegex(expr string) *DimExtractionFn {
-	return &DimExtractionFn{
-		Type: "regex",
-		Expr: expr,
+func DimLookup(dimension, outputName, lookup string, retainMissingValue bool, replaceMissingValueWith string) DimSpec {
+	return &LookupDimension{
+		Type:                    "lookup",
+		Dimension:               dimension,
+		OutputName:              outputName,
+		Name:                    lookup,
+		RetainMissingValue:      retainMissingValue,
+		ReplaceMissingValueWith: replaceMissingValueWith,
 	}
 }
 
-func DimExFnPartial(expr string) *DimExtractionFn {
-	return &DimExtractionFn{
-		Type: "partial",
-		Expr: expr,
+func DimMapLookup(dimension, outputName string, lookup map[string]string, retainMissingValue bool, replaceMissingValueWith string) DimSpec {
+	return &LookupDimension{
+		Type:       "lookup",
+		Dimension:  dimension,
+		OutputName: outputName,
+		Lookup: &MapLookup{
+			Type: "map",
+			Map:  lookup,
+		},
+		RetainMissingValue:      retainMissingValue,
+		ReplaceMissingValueWith: replaceMissingValueWith,
 	}
 }
 
-func DimExFnSearchQuerySpec(query *SearchQuery) *DimExtractionFn {
-	return &DimExtractionFn{
-		Type:  "searchQuery",
-		Query: query,
+// DimListFiltered keeps the listed values when isWhitelist is true, and all
+// the others when it's false.
+func DimListFiltered(delegate DimSpec, values []string, isWhitelist bool) DimSpec {
+	return &ListFilteredDimension{
+		Type:        "listFiltered",
+		Delegate:    delegate,
+		Values:      values,
+		IsWhitelist: &isWhitelist,
 	}
 }
 
-func DimExFnTime(timeFormat, timeZone string, locale string, granularity string, asMillis bool) *DimExtractionFn {
-	return &DimExtractionFn{
-		Type:        "timeFormat",
-		Format:      timeFormat,
-		TimeZone:    timeZone,
-		Locale:      locale,
-		Granularity: granularity,
-		AsMillis:    asMillis,
+func DimRegexFiltered(delegate DimSpec, pattern string) DimSpec {
+	return &RegexFilteredDimension{
+		Type:     "regexFiltered",
+		Delegate: delegate,
+		Pattern:  pattern,
 	}
 }
 
-func DimExFnJavascript(function string) *DimExtractionFn {
-	return &DimExtractionFn{
-		Type:     "javascript",
-		Function: function,
+func DimPrefixFiltered(delegate DimSpec, prefix string) DimSpec {
+	return &PrefixFilteredDimension{
+		Type:     "prefixFiltered",
+		Delegate: delegate,
+		Prefix:   prefix,
+	}
+}
+
+// Deprecated: use ExFnRegex.
+func DimExFnRegex(expr string) ExtractionFn {
+	return ExFnRegex(expr)
+}
+
+// Deprecated: use ExFnPartial.
+func DimExFnPartial(expr string) ExtractionFn {
+	return ExFnPartial(expr)
+}
+
+// Deprecated: use ExFnSearchQuery.
+func DimExFnSearchQuerySpec(query *SearchQuery) ExtractionFn {
+	return ExFnSearchQuery(query)
+}
+
+// Deprecated: use ExFnTimeFormat.
+func DimExFnTime(timeFormat, timeZone string, locale string, granularity string, asMillis bool) ExtractionFn {
+	var gran Granularity
+	if granularity != "" {
+		gran = SimpleGran(granularity)
+	}
+	return ExFnTimeFormat(timeFormat, timeZone, locale, gran, asMillis)
+}
+
+// Deprecated: use ExFnJavaScript.
+func DimExFnJavascript(function string) ExtractionFn {
+	return ExFnJavaScript(function)
+}
+
+// dimOutputName returns the name results carry the dimension under.
+func dimOutputName(dim DimSpec) string {
+	switch d := dim.(type) {
+	case string:
+		return d
+	case Dimension:
+		return dimOutputName(&d)
+	case TimeExtractionDimensionSpec:
+		return dimOutputName(&d)
+	case LookupDimension:
+		return dimOutputName(&d)
+	case ListFilteredDimension:
+		return dimOutputName(d.Delegate)
+	case RegexFilteredDimension:
+		return dimOutputName(d.Delegate)
+	case PrefixFilteredDimension:
+		return dimOutputName(d.Delegate)
+	case *Dimension:
+		if d.OutputName != "" {
+			return d.OutputName
+		}
+		return d.Dimension
+	case *TimeExtractionDimensionSpec:
+		if d.OutputName != "" {
+			return d.OutputName
+		}
+		return d.Dimension
+	case *LookupDimension:
+		if d.OutputName != "" {
+			return d.OutputName
+		}
+		return d.Dimension
+	case *ListFilteredDimension:
+		return dimOutputName(d.Delegate)
+	case *RegexFilteredDimension:
+		return dimOutputName(d.Delegate)
+	case *PrefixFilteredDimension:
+		return dimOutputName(d.Delegate)
 	}
+	return ""
 }
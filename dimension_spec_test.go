@@ -0,0 +1,36 @@
+package godruid
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDimSpecs(t *testing.T) {
+	Convey("TestDimSpecs", t, func() {
+		tags := DimDefault("tags", "tag")
+		cases := []struct {
+			dim  DimSpec
+			json string
+		}{
+			{DimDefaultTyped("added", "added", OutputTypeLong), `{"type":"default","dimension":"added","outputName":"added","outputType":"LONG"}`},
+			{DimLookup("country", "name", "countries", true, ""),
+				`{"type":"lookup","dimension":"country","outputName":"name","name":"countries","retainMissingValue":true}`},
+			{DimMapLookup("country", "name", map[string]string{"US": "United States"}, false, "Other"),
+				`{"type":"lookup","dimension":"country","outputName":"name","lookup":{"type":"map","map":{"US":"United States"}},"replaceMissingValueWith":"Other"}`},
+			{DimListFiltered(tags, []string{"a", "b"}, true),
+				`{"type":"listFiltered","delegate":{"type":"default","dimension":"tags","outputName":"tag"},"values":["a","b"],"isWhitelist":true}`},
+			{DimListFiltered(tags, []string{"a"}, false),
+				`{"type":"listFiltered","delegate":{"type":"default","dimension":"tags","outputName":"tag"},"values":["a"],"isWhitelist":false}`},
+			{DimRegexFiltered("tags", "^g.*"), `{"type":"regexFiltered","delegate":"tags","pattern":"^g.*"}`},
+			{DimPrefixFiltered(DimDefaultTyped("tags", "tag", OutputTypeString), "go"),
+				`{"type":"prefixFiltered","delegate":{"type":"default","dimension":"tags","outputName":"tag","outputType":"STRING"},"prefix":"go"}`},
+		}
+		for _, c := range cases {
+			data, err := json.Marshal(c.dim)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, c.json)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package godruid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error codes Druid puts in the "error" field of a failed query response.
+// Check http://druid.io/docs/latest/querying/querying.html#query-errors for detail description.
+const (
+	ErrCodeQueryTimeout          = "Query timeout"
+	ErrCodeQueryInterrupted      = "Query interrupted"
+	ErrCodeQueryCancelled        = "Query cancelled"
+	ErrCodeQueryCapacityExceeded = "Query capacity exceeded"
+	ErrCodeUnsupportedOperation  = "Unsupported operation"
+	ErrCodeResourceLimitExceeded = "Resource limit exceeded"
+	ErrCodeTruncatedResponse     = "Truncated response context"
+	ErrCodeUnknownException      = "Unknown exception"
+)
+
+// DruidError is returned by the Client whenever the broker answers with a non-200 status.
+type DruidError struct {
+	StatusCode int    `json:"-"`
+	Status     string `json:"-"`
+
+	Err          string `json:"error"`
+	ErrorMessage string `json:"errorMessage"`
+	ErrorClass   string `json:"errorClass"`
+	Host         string `json:"host"`
+}
+
+func (e *DruidError) Error() string {
+	msg := e.Status
+	if e.Err != "" {
+		msg += ": " + e.Err
+	}
+	if e.ErrorMessage != "" {
+		msg += ": " + e.ErrorMessage
+	}
+	if e.ErrorClass != "" {
+		msg += " (" + e.ErrorClass + ")"
+	}
+	if e.Host != "" {
+		msg += " on " + e.Host
+	}
+	return msg
+}
+
+// newDruidError builds a DruidError from a failed response. Bodies that are not
+// Druid's JSON error object (e.g. from a proxy) end up in ErrorMessage as is.
+func newDruidError(resp *http.Response, body []byte) *DruidError {
+	e := &DruidError{}
+	if json.Unmarshal(body, e) != nil || (e.Err == "" && e.ErrorMessage == "" && e.ErrorClass == "") {
+		e = &DruidError{ErrorMessage: strings.TrimSpace(string(body))}
+	}
+	e.StatusCode = resp.StatusCode
+	e.Status = resp.Status
+	if e.Status == "" {
+		e.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return e
+}
+
+// is reports whether the error carries the given error code or one of the given
+// error classes. Classes are matched on their simple name so that both the
+// io.druid and org.apache.druid packages are recognised.
+func (e *DruidError) is(code string, classes ...string) bool {
+	if e.Err == code {
+		return true
+	}
+	class := e.ErrorClass[strings.LastIndex(e.ErrorClass, ".")+1:]
+	for _, c := range classes {
+		if class == c {
+			return true
+		}
+	}
+	return false
+}
+
+func asDruidError(err error) (*DruidError, bool) {
+	var e *DruidError
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+func IsQueryTimeout(err error) bool {
+	e, ok := asDruidError(err)
+	return ok && e.is(ErrCodeQueryTimeout, "QueryTimeoutException", "TimeoutException")
+}
+
+func IsQueryInterrupted(err error) bool {
+	e, ok := asDruidError(err)
+	return ok && e.is(ErrCodeQueryInterrupted, "QueryInterruptedException")
+}
+
+func IsQueryCancelled(err error) bool {
+	e, ok := asDruidError(err)
+	return ok && e.is(ErrCodeQueryCancelled, "CancellationException")
+}
+
+func IsQueryCapacityExceeded(err error) bool {
+	e, ok := asDruidError(err)
+	return ok && e.is(ErrCodeQueryCapacityExceeded, "QueryCapacityExceededException")
+}
+
+func IsResourceLimitExceeded(err error) bool {
+	e, ok := asDruidError(err)
+	return ok && e.is(ErrCodeResourceLimitExceeded, "ResourceLimitExceededException")
+}
+
+func IsUnsupportedOperation(err error) bool {
+	e, ok := asDruidError(err)
+	return ok && e.is(ErrCodeUnsupportedOperation, "QueryUnsupportedException", "UnsupportedOperationException")
+}
@@ -0,0 +1,34 @@
+package godruid
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDruidError(t *testing.T) {
+	Convey("TestDruidError", t, func() {
+		resp := &http.Response{StatusCode: 504, Status: "504 Gateway Timeout"}
+		err := error(newDruidError(resp, []byte(`{
+			"error": "Query timeout",
+			"errorMessage": "Timeout waiting for task.",
+			"errorClass": "java.util.concurrent.TimeoutException",
+			"host": "druid1.example.com:8083"
+		}`)))
+
+		So(err.(*DruidError).StatusCode, ShouldEqual, 504)
+		So(err.(*DruidError).Host, ShouldEqual, "druid1.example.com:8083")
+		So(IsQueryTimeout(err), ShouldBeTrue)
+		So(IsQueryTimeout(fmt.Errorf("wrapped: %w", err)), ShouldBeTrue)
+		So(IsResourceLimitExceeded(err), ShouldBeFalse)
+
+		err = newDruidError(&http.Response{StatusCode: 400}, []byte(`{"errorClass": "org.apache.druid.query.ResourceLimitExceededException"}`))
+		So(IsResourceLimitExceeded(err), ShouldBeTrue)
+
+		err = newDruidError(&http.Response{StatusCode: 502}, []byte("<html>Bad Gateway</html>"))
+		So(err.Error(), ShouldEqual, "502 Bad Gateway: <html>Bad Gateway</html>")
+		So(IsQueryInterrupted(err), ShouldBeFalse)
+	})
+}
@@ -1,15 +1,360 @@
 package godruid
 
-type ExtractionFn interface{}
+import (
+	"encoding/json"
+)
 
+// ExtractionFn transforms dimension values. The same extraction functions are
+// used by dimension specs and by filters.
+// Check http://druid.io/docs/latest/querying/dimensionspecs.html#extraction-functions for detail description.
+type ExtractionFn interface {
+	ExtractionType() string
+}
+
+// RegexExtractionFn returns the group Index of the first match of Expr. Druid
+// takes group 1 when Index is nil; 0 is the whole match.
+type RegexExtractionFn struct {
+	Type                    string `json:"type"`
+	Expr                    string `json:"expr"`
+	Index                   *int   `json:"index,omitempty"`
+	ReplaceMissingValue     bool   `json:"replaceMissingValue,omitempty"`
+	ReplaceMissingValueWith string `json:"replaceMissingValueWith,omitempty"`
+}
+
+type PartialExtractionFn struct {
+	Type string `json:"type"`
+	Expr string `json:"expr"`
+}
+
+type SearchQueryExtractionFn struct {
+	Type  string       `json:"type"`
+	Query *SearchQuery `json:"query"`
+}
+
+type JavaScriptExtractionFn struct {
+	Type      string `json:"type"`
+	Function  string `json:"function"`
+	Injective bool   `json:"injective,omitempty"`
+}
+
+// TimeFormatExtractionFn formats __time (or a dimension holding timestamps)
+// with a Joda DateTimeFormat pattern.
+type TimeFormatExtractionFn struct {
+	Type        string      `json:"type"`
+	Format      string      `json:"format,omitempty"`
+	TimeZone    string      `json:"timeZone,omitempty"`
+	Locale      string      `json:"locale,omitempty"`
+	Granularity Granularity `json:"granularity,omitempty"`
+	AsMillis    bool        `json:"asMillis,omitempty"`
+}
+
+// TimeExtractionFn reparses dimension values from TimeFormat to ResultFormat.
+type TimeExtractionFn struct {
+	Type         string `json:"type"`
+	TimeFormat   string `json:"timeFormat"`
+	ResultFormat string `json:"resultFormat"`
+	Joda         bool   `json:"joda,omitempty"`
+}
+
+// LookupExtractionFn maps values through a lookup given inline with the query.
+type LookupExtractionFn struct {
+	Type                    string     `json:"type"`
+	Lookup                  *MapLookup `json:"lookup"`
+	RetainMissingValue      bool       `json:"retainMissingValue,omitempty"`
+	ReplaceMissingValueWith string     `json:"replaceMissingValueWith,omitempty"`
+	Injective               *bool      `json:"injective,omitempty"`
+	Optimize                *bool      `json:"optimize,omitempty"`
+}
+
+type MapLookup struct {
+	Type       string            `json:"type"`
+	Map        map[string]string `json:"map"`
+	IsOneToOne bool              `json:"isOneToOne,omitempty"`
+}
+
+// RegisteredLookupExtractionFn maps values through a lookup registered on the cluster.
 type RegisteredLookupExtractionFn struct {
+	Type                    string `json:"type"`
+	Lookup                  string `json:"lookup"`
+	RetainMissingValue      bool   `json:"retainMissingValue,omitempty"`
+	ReplaceMissingValueWith string `json:"replaceMissingValueWith,omitempty"`
+	Injective               *bool  `json:"injective,omitempty"`
+	Optimize                *bool  `json:"optimize,omitempty"`
+}
+
+type SubstringExtractionFn struct {
 	Type   string `json:"type"`
-	Lookup string `json:"lookup"`
+	Index  int    `json:"index"`
+	Length *int   `json:"length,omitempty"`
 }
 
-type TimeExtractionFn struct {
-	Type     string `json:"type"`
-	Format   string `json:"format"`
-	TimeZone string `json:"timeZone"`
-	Locale   string `json:"locale"`
+type StrlenExtractionFn struct {
+	Type string `json:"type"`
+}
+
+// CaseExtractionFn is the "upper" or the "lower" extraction function.
+type CaseExtractionFn struct {
+	Type   string `json:"type"`
+	Locale string `json:"locale,omitempty"`
+}
+
+type BucketExtractionFn struct {
+	Type   string `json:"type"`
+	Size   int    `json:"size,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+}
+
+type StringFormatExtractionFn struct {
+	Type         string `json:"type"`
+	Format       string `json:"format"`
+	NullHandling string `json:"nullHandling,omitempty"`
+}
+
+type CascadeExtractionFn struct {
+	Type          string         `json:"type"`
+	ExtractionFns []ExtractionFn `json:"extractionFns"`
+}
+
+// RawExtractionFn holds an extraction function this package has no type for.
+type RawExtractionFn map[string]interface{}
+
+func (fn RegexExtractionFn) ExtractionType() string            { return fn.Type }
+func (fn PartialExtractionFn) ExtractionType() string          { return fn.Type }
+func (fn SearchQueryExtractionFn) ExtractionType() string      { return fn.Type }
+func (fn JavaScriptExtractionFn) ExtractionType() string       { return fn.Type }
+func (fn TimeFormatExtractionFn) ExtractionType() string       { return fn.Type }
+func (fn TimeExtractionFn) ExtractionType() string             { return fn.Type }
+func (fn LookupExtractionFn) ExtractionType() string           { return fn.Type }
+func (fn RegisteredLookupExtractionFn) ExtractionType() string { return fn.Type }
+func (fn SubstringExtractionFn) ExtractionType() string        { return fn.Type }
+func (fn StrlenExtractionFn) ExtractionType() string           { return fn.Type }
+func (fn CaseExtractionFn) ExtractionType() string             { return fn.Type }
+func (fn BucketExtractionFn) ExtractionType() string           { return fn.Type }
+func (fn StringFormatExtractionFn) ExtractionType() string     { return fn.Type }
+func (fn CascadeExtractionFn) ExtractionType() string          { return fn.Type }
+func (fn RawExtractionFn) ExtractionType() string {
+	t, _ := fn["type"].(string)
+	return t
+}
+
+// UnmarshalExtractionFn decodes any extraction function into its typed form.
+// A JSON null gives a nil ExtractionFn.
+func UnmarshalExtractionFn(data []byte) (ExtractionFn, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	var fn ExtractionFn
+	switch head.Type {
+	case "regex":
+		fn = &RegexExtractionFn{}
+	case "partial":
+		fn = &PartialExtractionFn{}
+	case "searchQuery":
+		fn = &SearchQueryExtractionFn{}
+	case "javascript":
+		fn = &JavaScriptExtractionFn{}
+	case "timeFormat":
+		fn = &TimeFormatExtractionFn{}
+	case "time":
+		fn = &TimeExtractionFn{}
+	case "lookup":
+		fn = &LookupExtractionFn{}
+	case "registeredLookup":
+		fn = &RegisteredLookupExtractionFn{}
+	case "substring":
+		fn = &SubstringExtractionFn{}
+	case "strlen":
+		fn = &StrlenExtractionFn{}
+	case "upper", "lower":
+		fn = &CaseExtractionFn{}
+	case "bucket":
+		fn = &BucketExtractionFn{}
+	case "stringFormat":
+		fn = &StringFormatExtractionFn{}
+	case "cascade":
+		fn = &CascadeExtractionFn{}
+	default:
+		raw := RawExtractionFn{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+	if err := json.Unmarshal(data, fn); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+func (fn *CascadeExtractionFn) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type          string            `json:"type"`
+		ExtractionFns []json.RawMessage `json:"extractionFns"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	fn.Type = raw.Type
+	fn.ExtractionFns = nil
+	for _, r := range raw.ExtractionFns {
+		f, err := UnmarshalExtractionFn(r)
+		if err != nil {
+			return err
+		}
+		fn.ExtractionFns = append(fn.ExtractionFns, f)
+	}
+	return nil
+}
+
+func (fn *TimeFormatExtractionFn) UnmarshalJSON(data []byte) error {
+	type alias TimeFormatExtractionFn
+	var raw struct {
+		*alias
+		Granularity json.RawMessage `json:"granularity"`
+	}
+	raw.alias = (*alias)(fn)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	gran, err := UnmarshalGranularity(raw.Granularity)
+	if err != nil {
+		return err
+	}
+	fn.Granularity = gran
+	return nil
+}
+
+func ExFnRegex(expr string) ExtractionFn {
+	return &RegexExtractionFn{
+		Type: "regex",
+		Expr: expr,
+	}
+}
+
+func ExFnPartial(expr string) ExtractionFn {
+	return &PartialExtractionFn{
+		Type: "partial",
+		Expr: expr,
+	}
+}
+
+func ExFnSearchQuery(query *SearchQuery) ExtractionFn {
+	return &SearchQueryExtractionFn{
+		Type:  "searchQuery",
+		Query: query,
+	}
+}
+
+func ExFnJavaScript(function string) ExtractionFn {
+	return &JavaScriptExtractionFn{
+		Type:     "javascript",
+		Function: function,
+	}
+}
+
+func ExFnTimeFormat(format, timeZone, locale string, granularity Granularity, asMillis bool) ExtractionFn {
+	return &TimeFormatExtractionFn{
+		Type:        "timeFormat",
+		Format:      format,
+		TimeZone:    timeZone,
+		Locale:      locale,
+		Granularity: granularity,
+		AsMillis:    asMillis,
+	}
+}
+
+func ExFnTime(timeFormat, resultFormat string, joda bool) ExtractionFn {
+	return &TimeExtractionFn{
+		Type:         "time",
+		TimeFormat:   timeFormat,
+		ResultFormat: resultFormat,
+		Joda:         joda,
+	}
+}
+
+// ExFnLookup maps values through lookup. Values missing from it become null,
+// unless retainMissingValue is set (they are then kept as they are), or
+// replaceMissingValueWith is not empty.
+func ExFnLookup(lookup map[string]string, isOneToOne, retainMissingValue bool, replaceMissingValueWith string) ExtractionFn {
+	return &LookupExtractionFn{
+		Type: "lookup",
+		Lookup: &MapLookup{
+			Type:       "map",
+			Map:        lookup,
+			IsOneToOne: isOneToOne,
+		},
+		RetainMissingValue:      retainMissingValue,
+		ReplaceMissingValueWith: replaceMissingValueWith,
+	}
+}
+
+func ExFnRegisteredLookup(lookup string, retainMissingValue bool, replaceMissingValueWith string) ExtractionFn {
+	return &RegisteredLookupExtractionFn{
+		Type:                    "registeredLookup",
+		Lookup:                  lookup,
+		RetainMissingValue:      retainMissingValue,
+		ReplaceMissingValueWith: replaceMissingValueWith,
+	}
+}
+
+// ExFnSubstring keeps length characters from index. A negative length keeps
+// the rest of the value.
+func ExFnSubstring(index, length int) ExtractionFn {
+	fn := &SubstringExtractionFn{
+		Type:  "substring",
+		Index: index,
+	}
+	if length >= 0 {
+		fn.Length = &length
+	}
+	return fn
+}
+
+func ExFnStrlen() ExtractionFn {
+	return &StrlenExtractionFn{Type: "strlen"}
+}
+
+func ExFnUpper(locale string) ExtractionFn {
+	return &CaseExtractionFn{
+		Type:   "upper",
+		Locale: locale,
+	}
+}
+
+func ExFnLower(locale string) ExtractionFn {
+	return &CaseExtractionFn{
+		Type:   "lower",
+		Locale: locale,
+	}
+}
+
+func ExFnBucket(size, offset int) ExtractionFn {
+	return &BucketExtractionFn{
+		Type:   "bucket",
+		Size:   size,
+		Offset: offset,
+	}
+}
+
+// ExFnStringFormat formats values with a printf like format, e.g. "[%s]".
+// nullHandling is one of "nullString", "emptyString" or "returnNull".
+func ExFnStringFormat(format, nullHandling string) ExtractionFn {
+	return &StringFormatExtractionFn{
+		Type:         "stringFormat",
+		Format:       format,
+		NullHandling: nullHandling,
+	}
+}
+
+func ExFnCascade(fns ...ExtractionFn) ExtractionFn {
+	return &CascadeExtractionFn{
+		Type:          "cascade",
+		ExtractionFns: fns,
+	}
 }
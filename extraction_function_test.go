@@ -0,0 +1,82 @@
+package godruid
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExtractionFns(t *testing.T) {
+	Convey("TestExtractionFns", t, func() {
+		whole := 0
+		oneToOne := true
+		cases := []struct {
+			fn   ExtractionFn
+			json string
+		}{
+			{ExFnRegex("(\\w+)"), `{"type":"regex","expr":"(\\w+)"}`},
+			{&RegexExtractionFn{Type: "regex", Expr: "\\w+", Index: &whole, ReplaceMissingValue: true, ReplaceMissingValueWith: "none"},
+				`{"type":"regex","expr":"\\w+","index":0,"replaceMissingValue":true,"replaceMissingValueWith":"none"}`},
+			{ExFnPartial("^go"), `{"type":"partial","expr":"^go"}`},
+			{ExFnSearchQuery(SearchQueryInsensitiveContains("druid")), `{"type":"searchQuery","query":{"type":"insensitive_contains","value":"druid"}}`},
+			{ExFnJavaScript("function(x) { return x }"), `{"type":"javascript","function":"function(x) { return x }"}`},
+			{ExFnTimeFormat("yyyy-MM", "Europe/Paris", "fr", GranDay, true),
+				`{"type":"timeFormat","format":"yyyy-MM","timeZone":"Europe/Paris","locale":"fr","granularity":"day","asMillis":true}`},
+			{ExFnTime("dd/MM/yyyy", "yyyy-MM-dd", true), `{"type":"time","timeFormat":"dd/MM/yyyy","resultFormat":"yyyy-MM-dd","joda":true}`},
+			{ExFnLookup(map[string]string{"US": "United States"}, true, false, "Other"),
+				`{"type":"lookup","lookup":{"type":"map","map":{"US":"United States"},"isOneToOne":true},"replaceMissingValueWith":"Other"}`},
+			{&RegisteredLookupExtractionFn{Type: "registeredLookup", Lookup: "countries", RetainMissingValue: true, Injective: &oneToOne},
+				`{"type":"registeredLookup","lookup":"countries","retainMissingValue":true,"injective":true}`},
+			{ExFnSubstring(0, 2), `{"type":"substring","index":0,"length":2}`},
+			{ExFnSubstring(3, -1), `{"type":"substring","index":3}`},
+			{ExFnStrlen(), `{"type":"strlen"}`},
+			{ExFnUpper("fr"), `{"type":"upper","locale":"fr"}`},
+			{ExFnLower(""), `{"type":"lower"}`},
+			{ExFnBucket(10, 2), `{"type":"bucket","size":10,"offset":2}`},
+			{ExFnStringFormat("[%s]", "emptyString"), `{"type":"stringFormat","format":"[%s]","nullHandling":"emptyString"}`},
+			{ExFnCascade(ExFnSubstring(0, 3), ExFnUpper("")),
+				`{"type":"cascade","extractionFns":[{"type":"substring","index":0,"length":3},{"type":"upper"}]}`},
+		}
+
+		Convey("round trip", func() {
+			for _, c := range cases {
+				data, err := json.Marshal(c.fn)
+				So(err, ShouldBeNil)
+				So(string(data), ShouldEqual, c.json)
+				fn, err := UnmarshalExtractionFn(data)
+				So(err, ShouldBeNil)
+				So(fn, ShouldResemble, c.fn)
+			}
+		})
+
+		Convey("unknown types are kept raw", func() {
+			fn, err := UnmarshalExtractionFn([]byte(`{"type":"someExtension","x":1}`))
+			So(err, ShouldBeNil)
+			So(fn, ShouldResemble, RawExtractionFn{"type": "someExtension", "x": 1.0})
+			So(fn.ExtractionType(), ShouldEqual, "someExtension")
+
+			fn, err = UnmarshalExtractionFn([]byte(`null`))
+			So(err, ShouldBeNil)
+			So(fn, ShouldBeNil)
+		})
+
+		Convey("in dimension specs", func() {
+			for _, c := range cases {
+				dim := DimExtraction("page", "out", c.fn).(*Dimension)
+				data, err := json.Marshal(dim)
+				So(err, ShouldBeNil)
+				var decoded Dimension
+				So(json.Unmarshal(data, &decoded), ShouldBeNil)
+				So(&decoded, ShouldResemble, dim)
+
+				spec := &TimeExtractionDimensionSpec{Type: "extraction", Dimension: "__time", OutputName: "out", ExtractionFunction: c.fn}
+				data, err = json.Marshal(spec)
+				So(err, ShouldBeNil)
+				var decodedSpec TimeExtractionDimensionSpec
+				So(json.Unmarshal(data, &decodedSpec), ShouldBeNil)
+				So(&decodedSpec, ShouldResemble, spec)
+			}
+		})
+	})
+}
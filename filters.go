@@ -1,5 +1,11 @@
 package godruid
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
 type Filter struct {
 	Type         string        `json:"type"`
 	Dimension    string        `json:"dimension,omitempty"`
@@ -8,12 +14,54 @@ type Filter struct {
 	Function     string        `json:"function,omitempty"`
 	Field        *Filter       `json:"field,omitempty"`
 	Fields       []*Filter     `json:"fields,omitempty"`
-	Upper        float32       `json:"upper,omitempty"`
-	Lower        float32       `json:"lower,omitempty"`
+	Upper        interface{}   `json:"upper,omitempty"`
+	Lower        interface{}   `json:"lower,omitempty"`
 	Ordering     Ordering      `json:"ordering,omitempty"`
 	UpperStrict  bool          `json:"upperStrict,omitempty"`
 	LowerStrict  bool          `json:"lowerStrict,omitempty"`
-	ExtractionFn *ExtractionFn `json:"extractionFn,omitempty"`
+	ExtractionFn ExtractionFn  `json:"extractionFn,omitempty"`
+	Values       []interface{} `json:"values,omitempty"`
+	Escape       string        `json:"escape,omitempty"`
+	Intervals    []Interval    `json:"intervals,omitempty"`
+	Query        *SearchQuery  `json:"query,omitempty"`
+	Expression   string        `json:"expression,omitempty"`
+	Dimensions   []DimSpec     `json:"dimensions,omitempty"`
+	Bound        *SpatialBound `json:"bound,omitempty"`
+}
+
+// jsonNull is used as the Value of a selector filter that matches nulls, since
+// a nil Value is left out of the JSON altogether.
+var jsonNull = json.RawMessage("null")
+
+// UnmarshalJSON keeps an explicit "value": null, so that FilterSelectorNull
+// survives a round trip, and decodes the extraction function into its type.
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	type alias Filter
+	var raw struct {
+		*alias
+		Value        json.RawMessage `json:"value"`
+		ExtractionFn json.RawMessage `json:"extractionFn"`
+	}
+	raw.alias = (*alias)(f)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	fn, err := UnmarshalExtractionFn(raw.ExtractionFn)
+	if err != nil {
+		return err
+	}
+	f.ExtractionFn = fn
+	f.Value = nil
+	switch string(raw.Value) {
+	case "":
+	case "null":
+		f.Value = jsonNull
+	default:
+		if err := json.Unmarshal(raw.Value, &f.Value); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type Ordering string
@@ -33,38 +81,138 @@ func FilterSelector(dimension string, value interface{}) *Filter {
 	}
 }
 
-func FilterUpperBound(dimension string, ordering Ordering, bound float32, strict bool) *Filter {
+// FilterSelectorNull matches the rows where dimension is null (or empty,
+// unless Druid runs in SQL compatible null handling mode).
+func FilterSelectorNull(dimension string) *Filter {
+	return &Filter{
+		Type:      "selector",
+		Dimension: dimension,
+		Value:     jsonNull,
+	}
+}
+
+func FilterIn(dimension string, values ...interface{}) *Filter {
+	return &Filter{
+		Type:      "in",
+		Dimension: dimension,
+		Values:    values,
+	}
+}
+
+// FilterLike takes a SQL LIKE pattern, where "%" matches any string and "_"
+// any single character. escape, if not empty, makes them match literally.
+func FilterLike(dimension, pattern, escape string) *Filter {
+	return &Filter{
+		Type:      "like",
+		Dimension: dimension,
+		Pattern:   pattern,
+		Escape:    escape,
+	}
+}
+
+func FilterInterval(dimension string, intervals ...Interval) *Filter {
+	return &Filter{
+		Type:      "interval",
+		Dimension: dimension,
+		Intervals: intervals,
+	}
+}
+
+func FilterSearch(dimension string, query *SearchQuery) *Filter {
+	return &Filter{
+		Type:      "search",
+		Dimension: dimension,
+		Query:     query,
+	}
+}
+
+func FilterExpression(expression string) *Filter {
+	return &Filter{
+		Type:       "expression",
+		Expression: expression,
+	}
+}
+
+func FilterColumnComparison(dimensions ...DimSpec) *Filter {
+	return &Filter{
+		Type:       "columnComparison",
+		Dimensions: dimensions,
+	}
+}
+
+func FilterTrue() *Filter {
+	return &Filter{Type: "true"}
+}
+
+func FilterFalse() *Filter {
+	return &Filter{Type: "false"}
+}
+
+func FilterSpatial(dimension string, bound *SpatialBound) *Filter {
+	return &Filter{
+		Type:      "spatial",
+		Dimension: dimension,
+		Bound:     bound,
+	}
+}
+
+// Bounds may be strings or any integer or floating point number. They are sent
+// as strings, which is what Druid expects, so that no precision is lost on
+// large values such as epoch milliseconds. A nil bound leaves that side open.
+func FilterUpperBound(dimension string, ordering Ordering, bound interface{}, strict bool) *Filter {
 	return &Filter{
 		Type:        "bound",
 		Dimension:   dimension,
 		Ordering:    ordering,
-		Upper:       bound,
+		Upper:       boundString(bound),
 		UpperStrict: strict,
 	}
 }
 
-func FilterLowerBound(dimension string, ordering Ordering, bound float32, strict bool) *Filter {
+func FilterLowerBound(dimension string, ordering Ordering, bound interface{}, strict bool) *Filter {
 	return &Filter{
 		Type:        "bound",
 		Dimension:   dimension,
 		Ordering:    ordering,
-		Lower:       bound,
+		Lower:       boundString(bound),
 		LowerStrict: strict,
 	}
 }
 
-func FilterLowerUpperBound(dimension string, ordering Ordering, lowerBound float32, lowerStrict bool, upperBound float32, upperStrict bool) *Filter {
+func FilterLowerUpperBound(dimension string, ordering Ordering, lowerBound interface{}, lowerStrict bool, upperBound interface{}, upperStrict bool) *Filter {
 	return &Filter{
 		Type:        "bound",
 		Dimension:   dimension,
 		Ordering:    ordering,
-		Lower:       lowerBound,
+		Lower:       boundString(lowerBound),
 		LowerStrict: lowerStrict,
-		Upper:       upperBound,
+		Upper:       boundString(upperBound),
 		UpperStrict: upperStrict,
 	}
 }
 
+func boundString(bound interface{}) interface{} {
+	switch b := bound.(type) {
+	case nil:
+		return nil
+	case string:
+		return b
+	case int:
+		return strconv.Itoa(b)
+	case int32:
+		return strconv.FormatInt(int64(b), 10)
+	case int64:
+		return strconv.FormatInt(b, 10)
+	case uint64:
+		return strconv.FormatUint(b, 10)
+	case float32:
+		return strconv.FormatFloat(float64(b), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(b, 'f', -1, 64)
+	}
+	return fmt.Sprint(bound)
+}
+
 func FilterRegex(dimension, pattern string) *Filter {
 	return &Filter{
 		Type:      "regex",
@@ -120,3 +268,41 @@ func joinFilters(filters []*Filter, connector string) *Filter {
 		Fields: filters,
 	}
 }
+
+// ---------------------------------
+// Spatial Bound
+// ---------------------------------
+
+type SpatialBound struct {
+	Type      string    `json:"type"`
+	MinCoords []float64 `json:"minCoords,omitempty"`
+	MaxCoords []float64 `json:"maxCoords,omitempty"`
+	Coords    []float64 `json:"coords,omitempty"`
+	Radius    float64   `json:"radius,omitempty"`
+	Abscissa  []float64 `json:"abscissa,omitempty"`
+	Ordinate  []float64 `json:"ordinate,omitempty"`
+}
+
+func SpatialBoundRectangular(minCoords, maxCoords []float64) *SpatialBound {
+	return &SpatialBound{
+		Type:      "rectangular",
+		MinCoords: minCoords,
+		MaxCoords: maxCoords,
+	}
+}
+
+func SpatialBoundRadius(coords []float64, radius float64) *SpatialBound {
+	return &SpatialBound{
+		Type:   "radius",
+		Coords: coords,
+		Radius: radius,
+	}
+}
+
+func SpatialBoundPolygon(abscissa, ordinate []float64) *SpatialBound {
+	return &SpatialBound{
+		Type:     "polygon",
+		Abscissa: abscissa,
+		Ordinate: ordinate,
+	}
+}
@@ -0,0 +1,46 @@
+package godruid
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFilters(t *testing.T) {
+	Convey("TestFilters", t, func() {
+		day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		cases := []struct {
+			filter *Filter
+			json   string
+		}{
+			{FilterSelectorNull("country"), `{"type":"selector","dimension":"country","value":null}`},
+			{FilterIn("country", "US", "CA"), `{"type":"in","dimension":"country","values":["US","CA"]}`},
+			{FilterLike("page", `50\%%`, `\`), `{"type":"like","dimension":"page","pattern":"50\\%%","escape":"\\"}`},
+			{FilterInterval("__time", NewInterval(day, day.AddDate(0, 0, 1))), `{"type":"interval","dimension":"__time","intervals":["2024-01-01T00:00:00.000Z/2024-01-02T00:00:00.000Z"]}`},
+			{FilterSearch("page", SearchQueryInsensitiveContains("druid")), `{"type":"search","dimension":"page","query":{"type":"insensitive_contains","value":"druid"}}`},
+			{FilterExpression("price * 2 == 10"), `{"type":"expression","expression":"price * 2 == 10"}`},
+			{FilterColumnComparison("a", "b"), `{"type":"columnComparison","dimensions":["a","b"]}`},
+			{FilterOr(FilterTrue(), FilterNot(FilterFalse())), `{"type":"or","fields":[{"type":"true"},{"type":"not","field":{"type":"false"}}]}`},
+			{FilterLowerBound("ts", NUMERIC, int64(1704067200123), false), `{"type":"bound","dimension":"ts","lower":"1704067200123","ordering":"numeric"}`},
+			{FilterUpperBound("price", NUMERIC, 0.1, true), `{"type":"bound","dimension":"price","upper":"0.1","ordering":"numeric","upperStrict":true}`},
+			{FilterLowerUpperBound("price", NUMERIC, nil, false, 5, true), `{"type":"bound","dimension":"price","upper":"5","ordering":"numeric","upperStrict":true}`},
+			{FilterLowerUpperBound("name", LEXICOGRAPHIC, "a", false, "m", true), `{"type":"bound","dimension":"name","upper":"m","lower":"a","ordering":"lexicographic","upperStrict":true}`},
+			{&Filter{Type: "selector", Dimension: "country", Value: "UNI", ExtractionFn: ExFnCascade(ExFnSubstring(0, 3), ExFnUpper(""), ExFnRegisteredLookup("codes", true, ""))},
+				`{"type":"selector","dimension":"country","value":"UNI","extractionFn":{"type":"cascade","extractionFns":[{"type":"substring","index":0,"length":3},{"type":"upper"},{"type":"registeredLookup","lookup":"codes","retainMissingValue":true}]}}`},
+			{FilterSpatial("coords", SpatialBoundRadius([]float64{1, 2}, 3)), `{"type":"spatial","dimension":"coords","bound":{"type":"radius","coords":[1,2],"radius":3}}`},
+		}
+		for _, c := range cases {
+			b, err := json.Marshal(c.filter)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, c.json)
+
+			back := &Filter{}
+			So(json.Unmarshal(b, back), ShouldBeNil)
+			b, err = json.Marshal(back)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, c.json)
+		}
+	})
+}
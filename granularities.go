@@ -1,27 +1,100 @@
 package godruid
 
-type Granlarity interface{}
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Granularity splits time into the buckets results are grouped by. It is
+// either a SimpleGran, a PeriodGranularity or a DurationGranularity.
+type Granularity interface {
+	// Bucket returns the start of the bucket t falls in.
+	Bucket(t time.Time) time.Time
+	// Next returns the start of the bucket following the one t falls in.
+	Next(t time.Time) time.Time
+}
+
+// Deprecated: use Granularity.
+type Granlarity = Granularity
 
 type SimpleGran string
 
 const (
 	GranAll        SimpleGran = "all"
 	GranNone       SimpleGran = "none"
+	GranSecond     SimpleGran = "second"
 	GranMinute     SimpleGran = "minute"
+	GranFiveMin    SimpleGran = "five_minute"
+	GranTenMin     SimpleGran = "ten_minute"
 	GranFifteenMin SimpleGran = "fifteen_minute"
 	GranThirtyMin  SimpleGran = "thirty_minute"
 	GranHour       SimpleGran = "hour"
+	GranSixHour    SimpleGran = "six_hour"
+	GranEightHour  SimpleGran = "eight_hour"
 	GranDay        SimpleGran = "day"
+	GranWeek       SimpleGran = "week"
+	GranMonth      SimpleGran = "month"
+	GranQuarter    SimpleGran = "quarter"
+	GranYear       SimpleGran = "year"
 )
 
-type granDuration struct {
-	Type string `json:"type"`
+// The periods of the simple granularities, which are all in UTC.
+var simpleGranPeriods = map[SimpleGran]Period{
+	GranSecond:     {Seconds: 1},
+	GranMinute:     {Minutes: 1},
+	GranFiveMin:    {Minutes: 5},
+	GranTenMin:     {Minutes: 10},
+	GranFifteenMin: {Minutes: 15},
+	GranThirtyMin:  {Minutes: 30},
+	GranHour:       {Hours: 1},
+	GranSixHour:    {Hours: 6},
+	GranEightHour:  {Hours: 8},
+	GranDay:        {Days: 1},
+	GranWeek:       {Weeks: 1},
+	GranMonth:      {Months: 1},
+	GranQuarter:    {Months: 3},
+	GranYear:       {Years: 1},
+}
 
-	Duration string `json:"duration"`
-	Origin   string `json:"origin,omitempty"`
+// endOfTime is where the single bucket of GranAll ends, like Druid's maximum
+// instant.
+var endOfTime = time.UnixMilli(math.MaxInt64 / 2).UTC()
+
+// Everything falls in the same bucket with GranAll: Bucket returns t as is
+// and Next returns a time after any interval. Unknown granularities behave
+// the same way.
+func (g SimpleGran) Bucket(t time.Time) time.Time {
+	start, _ := g.bucket(t)
+	return start
 }
 
-type granPeriod struct {
+func (g SimpleGran) Next(t time.Time) time.Time {
+	_, next := g.bucket(t)
+	return next
+}
+
+func (g SimpleGran) bucket(t time.Time) (time.Time, time.Time) {
+	if g == GranNone {
+		start := t.UTC().Truncate(time.Millisecond)
+		return start, start.Add(time.Millisecond)
+	}
+	p, ok := simpleGranPeriods[g]
+	if !ok {
+		return t, endOfTime
+	}
+	return periodBucket(p, defaultOrigin(p, time.UTC), t)
+}
+
+// PeriodGranularity buckets time by an ISO-8601 period, in TimeZone (UTC if
+// empty) and counting from Origin. Without an origin, buckets are counted
+// from 1970-01-01T00:00 in the time zone (the Monday after for periods made
+// of weeks only), so "P1D" buckets start at midnight and "P1M" buckets on the
+// first of the month.
+type PeriodGranularity struct {
 	Type string `json:"type"`
 
 	Period   string `json:"period"`
@@ -29,20 +102,207 @@ type granPeriod struct {
 	Origin   string `json:"origin,omitempty"`
 }
 
-func GranPeriod(period string, timeZone string, origin string) granPeriod {
-	return granPeriod{
+// An invalid period granularity behaves like GranAll; UnmarshalGranularity
+// reports the error.
+func (g PeriodGranularity) Bucket(t time.Time) time.Time {
+	start, _ := g.bucket(t)
+	return start
+}
+
+func (g PeriodGranularity) Next(t time.Time) time.Time {
+	_, next := g.bucket(t)
+	return next
+}
+
+func (g PeriodGranularity) bucket(t time.Time) (time.Time, time.Time) {
+	p, origin, err := g.parse()
+	if err != nil {
+		return t, endOfTime
+	}
+	return periodBucket(p, origin, t.In(origin.Location()))
+}
+
+func (g PeriodGranularity) parse() (Period, time.Time, error) {
+	p, err := ParsePeriod(g.Period)
+	if err != nil {
+		return p, time.Time{}, err
+	}
+	if p.approx() <= 0 {
+		return p, time.Time{}, fmt.Errorf("godruid: empty period %q", g.Period)
+	}
+	loc, err := loadLocation(g.TimeZone)
+	if err != nil {
+		return p, time.Time{}, err
+	}
+	if g.Origin == "" {
+		return p, defaultOrigin(p, loc), nil
+	}
+	// An origin without a zone is in the granularity's time zone.
+	origin, err := parseTimeIn(g.Origin, loc)
+	return p, origin.In(loc), err
+}
+
+// DurationGranularity buckets time by a fixed number of milliseconds, counting
+// from Origin (the epoch if empty).
+type DurationGranularity struct {
+	Type string `json:"type"`
+
+	Duration json.Number `json:"duration"`
+	Origin   string      `json:"origin,omitempty"`
+}
+
+// An invalid duration granularity behaves like GranAll; UnmarshalGranularity
+// reports the error.
+func (g DurationGranularity) Bucket(t time.Time) time.Time {
+	start, _ := g.bucket(t)
+	return start
+}
+
+func (g DurationGranularity) Next(t time.Time) time.Time {
+	_, next := g.bucket(t)
+	return next
+}
+
+func (g DurationGranularity) bucket(t time.Time) (time.Time, time.Time) {
+	d, origin, err := g.parse()
+	if err != nil {
+		return t, endOfTime
+	}
+	ms := t.UnixMilli() - origin
+	n := ms / d
+	if ms%d < 0 {
+		n--
+	}
+	start := time.UnixMilli(origin + n*d).UTC()
+	return start, start.Add(time.Duration(d) * time.Millisecond)
+}
+
+func (g DurationGranularity) parse() (int64, int64, error) {
+	d, err := strconv.ParseInt(string(g.Duration), 10, 64)
+	if err != nil || d <= 0 {
+		return 0, 0, fmt.Errorf("godruid: invalid duration %q", g.Duration)
+	}
+	if g.Origin == "" {
+		return d, 0, nil
+	}
+	origin, err := parseTime(g.Origin)
+	return d, origin.UnixMilli(), err
+}
+
+// GranPeriod takes an ISO-8601 period, e.g. "PT6H". timeZone and origin may
+// be empty.
+func GranPeriod(period string, timeZone string, origin string) PeriodGranularity {
+	return PeriodGranularity{
 		Type:     "period",
 		Period:   period,
-		TimeZone: "UTC",
+		TimeZone: timeZone,
 		Origin:   origin,
 	}
 }
 
-func GranDuration(duration string, origin string) granDuration {
-	return granDuration{
+// GranDuration takes the duration in milliseconds, e.g. "7200000".
+func GranDuration(duration string, origin string) DurationGranularity {
+	return DurationGranularity{
 		Type:     "duration",
-		Duration: duration,
+		Duration: json.Number(duration),
 		Origin:   origin,
 	}
+}
+
+// UnmarshalGranularity decodes any granularity Druid accepts: the name of a
+// simple one, or a "period", "duration", "all" or "none" object. A JSON null
+// gives a nil Granularity.
+func UnmarshalGranularity(data []byte) (Granularity, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		return parseSimpleGran(name)
+	}
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	switch head.Type {
+	case "period":
+		var g PeriodGranularity
+		if err := json.Unmarshal(data, &g); err != nil {
+			return nil, err
+		}
+		if _, _, err := g.parse(); err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "duration":
+		var g DurationGranularity
+		if err := json.Unmarshal(data, &g); err != nil {
+			return nil, err
+		}
+		if _, _, err := g.parse(); err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+	return parseSimpleGran(head.Type)
+}
+
+func parseSimpleGran(name string) (Granularity, error) {
+	g := SimpleGran(strings.ToLower(name))
+	if _, ok := simpleGranPeriods[g]; ok || g == GranAll || g == GranNone {
+		return g, nil
+	}
+	return nil, fmt.Errorf("godruid: unknown granularity %q", name)
+}
+
+// approx is the average length of the period, good enough to estimate how
+// many periods fit in a duration.
+func (p Period) approx() time.Duration {
+	day := 24 * time.Hour
+	return time.Duration(p.Years)*time.Duration(365.2425*float64(day)) +
+		time.Duration(p.Months)*time.Duration(30.436875*float64(day)) +
+		time.Duration(7*p.Weeks+p.Days)*day +
+		time.Duration(p.Hours)*time.Hour +
+		time.Duration(p.Minutes)*time.Minute +
+		time.Duration(p.Seconds)*time.Second +
+		time.Duration(p.Millis)*time.Millisecond
+}
 
+func defaultOrigin(p Period, loc *time.Location) time.Time {
+	if p.Weeks != 0 && p == (Period{Weeks: p.Weeks}) {
+		return time.Date(1970, 1, 5, 0, 0, 0, 0, loc)
+	}
+	return time.Date(1970, 1, 1, 0, 0, 0, 0, loc)
+}
+
+// periodBucket returns the bounds of the bucket t falls in, buckets being
+// origin plus a whole number of periods.
+func periodBucket(p Period, origin, t time.Time) (time.Time, time.Time) {
+	n := int(t.Sub(origin) / p.approx())
+	for p.AddTo(origin, n).After(t) {
+		n--
+	}
+	for !p.AddTo(origin, n+1).After(t) {
+		n++
+	}
+	return p.AddTo(origin, n), p.AddTo(origin, n+1)
+}
+
+// loadLocation reads a time zone as Druid does: an IANA name, or a fixed
+// offset such as "+05:30".
+func loadLocation(tz string) (*time.Location, error) {
+	switch {
+	case tz == "" || tz == "UTC" || tz == "Z":
+		return time.UTC, nil
+	case strings.HasPrefix(tz, "+") || strings.HasPrefix(tz, "-"):
+		t, err := time.Parse("-07:00", tz)
+		if err != nil {
+			return nil, fmt.Errorf("godruid: invalid time zone %q", tz)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(tz, offset), nil
+	}
+	return time.LoadLocation(tz)
 }
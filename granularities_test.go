@@ -0,0 +1,72 @@
+package godruid
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGranularities(t *testing.T) {
+	Convey("TestGranularities", t, func() {
+		utc := func(s string) time.Time {
+			t, err := time.Parse(time.RFC3339, s)
+			So(err, ShouldBeNil)
+			return t
+		}
+
+		Convey("Bucket and Next", func() {
+			cases := []struct {
+				gran       Granularity
+				t          string
+				start, end string
+			}{
+				{GranFiveMin, "2024-01-01T10:07:30Z", "2024-01-01T10:05:00Z", "2024-01-01T10:10:00Z"},
+				{GranWeek, "2024-01-03T10:00:00Z", "2024-01-01T00:00:00Z", "2024-01-08T00:00:00Z"},
+				{GranQuarter, "2024-05-10T00:00:00Z", "2024-04-01T00:00:00Z", "2024-07-01T00:00:00Z"},
+				{GranYear, "2024-12-31T23:59:59Z", "2024-01-01T00:00:00Z", "2025-01-01T00:00:00Z"},
+				// A 23 hours day, daylight saving time starting on 2024-03-10.
+				{GranPeriod("P1D", "America/Los_Angeles", ""), "2024-03-10T12:00:00Z", "2024-03-10T08:00:00Z", "2024-03-11T07:00:00Z"},
+				{GranPeriod("PT6H", "", "2024-01-01T03:00"), "2024-01-01T01:00:00Z", "2023-12-31T21:00:00Z", "2024-01-01T03:00:00Z"},
+				{GranPeriod("P1M", "+05:30", ""), "2024-01-31T20:00:00Z", "2024-01-31T18:30:00Z", "2024-02-29T18:30:00Z"},
+				{GranDuration("7200000", ""), "2024-01-01T03:30:00Z", "2024-01-01T02:00:00Z", "2024-01-01T04:00:00Z"},
+			}
+			for _, c := range cases {
+				So(c.gran.Bucket(utc(c.t)).Equal(utc(c.start)), ShouldBeTrue)
+				So(c.gran.Next(utc(c.t)).Equal(utc(c.end)), ShouldBeTrue)
+			}
+
+			all := GranAll.Next(utc("2024-01-01T00:00:00Z"))
+			So(all.After(utc("9999-12-31T00:00:00Z")), ShouldBeTrue)
+		})
+
+		Convey("JSON", func() {
+			data, err := json.Marshal(GranPeriod("P1D", "Europe/Paris", ""))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"type":"period","period":"P1D","timeZone":"Europe/Paris"}`)
+			data, err = json.Marshal(GranDuration("7200000", ""))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"type":"duration","duration":7200000}`)
+
+			for in, out := range map[string]Granularity{
+				`"DAY"`:                            GranDay,
+				`{"type":"all"}`:                   GranAll,
+				`{"type":"period","period":"P1W"}`: GranPeriod("P1W", "", ""),
+				`{"type":"duration","duration":60000,"origin":"2024-01-01"}`: GranDuration("60000", "2024-01-01"),
+			} {
+				g, err := UnmarshalGranularity([]byte(in))
+				So(err, ShouldBeNil)
+				So(g, ShouldResemble, out)
+			}
+			for _, bad := range []string{`"fortnight"`, `{"type":"period","period":"P1X"}`, `{"type":"period","period":"P1D","timeZone":"Mars/Olympus"}`} {
+				_, err := UnmarshalGranularity([]byte(bad))
+				So(err, ShouldNotBeNil)
+			}
+
+			fn, err := UnmarshalExtractionFn([]byte(`{"type":"timeFormat","format":"EEEE","granularity":"week"}`))
+			So(err, ShouldBeNil)
+			So(fn, ShouldResemble, ExFnTimeFormat("EEEE", "", "", GranWeek, false))
+		})
+	})
+}
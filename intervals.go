@@ -1,3 +1,344 @@
 package godruid
 
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Intervals interface{}
+
+// The ISO-8601 form Druid uses for timestamps.
+const druidTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Interval is a time range whose End is excluded, like Druid's intervals.
+// It serializes to the "start/end" form, so it can be used wherever Intervals
+// are expected, on its own or in a slice.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+func NewInterval(start, end time.Time) Interval {
+	return Interval{Start: start, End: end}
+}
+
+func IntervalFromDuration(start time.Time, duration time.Duration) Interval {
+	return Interval{Start: start, End: start.Add(duration)}
+}
+
+// IntervalLast returns the interval covering the given duration up to now,
+// e.g. IntervalLast(6 * time.Hour) for the last six hours.
+func IntervalLast(duration time.Duration) Interval {
+	end := time.Now().UTC().Truncate(time.Millisecond)
+	return Interval{Start: end.Add(-duration), End: end}
+}
+
+// ParseInterval reads any of the ISO-8601 interval forms Druid accepts:
+// "start/end", "start/period" and "period/end", e.g. "P1D/2024-01-01".
+// Timestamps without a zone are taken as UTC.
+func ParseInterval(s string) (Interval, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return Interval{}, fmt.Errorf("godruid: invalid interval %q", s)
+	}
+	first, second := parts[0], parts[1]
+	switch {
+	case strings.HasPrefix(first, "P"):
+		p, err := ParsePeriod(first)
+		if err != nil {
+			return Interval{}, err
+		}
+		end, err := parseTime(second)
+		if err != nil {
+			return Interval{}, err
+		}
+		return Interval{Start: p.AddTo(end, -1), End: end}, nil
+	case strings.HasPrefix(second, "P"):
+		start, err := parseTime(first)
+		if err != nil {
+			return Interval{}, err
+		}
+		p, err := ParsePeriod(second)
+		if err != nil {
+			return Interval{}, err
+		}
+		return Interval{Start: start, End: p.AddTo(start, 1)}, nil
+	}
+	start, err := parseTime(first)
+	if err != nil {
+		return Interval{}, err
+	}
+	end, err := parseTime(second)
+	if err != nil {
+		return Interval{}, err
+	}
+	return Interval{Start: start, End: end}, nil
+}
+
+// ParseIntervals turns the value of a query's Intervals field (a string, an
+// Interval, or a slice of either) into Intervals.
+func ParseIntervals(intervals Intervals) ([]Interval, error) {
+	switch v := intervals.(type) {
+	case nil:
+		return nil, nil
+	case Interval:
+		return []Interval{v}, nil
+	case []Interval:
+		return v, nil
+	case string:
+		i, err := ParseInterval(v)
+		if err != nil {
+			return nil, err
+		}
+		return []Interval{i}, nil
+	case []string:
+		res := make([]Interval, 0, len(v))
+		for _, s := range v {
+			i, err := ParseInterval(s)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, i)
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]Interval, 0, len(v))
+		for _, e := range v {
+			is, err := ParseIntervals(e)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, is...)
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("godruid: unsupported intervals %T", intervals)
+}
+
+func (i Interval) String() string {
+	return i.Start.Format(druidTimeFormat) + "/" + i.End.Format(druidTimeFormat)
+}
+
+func (i Interval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+func (i *Interval) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseInterval(s)
+	if err != nil {
+		return err
+	}
+	*i = parsed
+	return nil
+}
+
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+func (i Interval) Contains(t time.Time) bool {
+	return !t.Before(i.Start) && t.Before(i.End)
+}
+
+func (i Interval) Overlaps(other Interval) bool {
+	return i.Start.Before(other.End) && other.Start.Before(i.End)
+}
+
+// Align widens the interval to whole buckets of the granularity.
+func (i Interval) Align(gran Granularity) Interval {
+	aligned := Interval{Start: gran.Bucket(i.Start), End: gran.Bucket(i.End)}
+	if aligned.End.Before(i.End) {
+		aligned.End = gran.Next(aligned.End)
+	}
+	return aligned
+}
+
+// Split cuts the interval into consecutive pieces of the given duration;
+// the last piece may be shorter.
+func (i Interval) Split(d time.Duration) []Interval {
+	if d <= 0 {
+		return []Interval{i}
+	}
+	var res []Interval
+	for start := i.Start; start.Before(i.End); start = start.Add(d) {
+		end := start.Add(d)
+		if end.After(i.End) {
+			end = i.End
+		}
+		res = append(res, Interval{Start: start, End: end})
+	}
+	return res
+}
+
+// MergeIntervals sorts the intervals and joins the ones that overlap or touch.
+func MergeIntervals(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sorted := make([]Interval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].Start.Before(sorted[b].Start) })
+
+	res := []Interval{sorted[0]}
+	for _, i := range sorted[1:] {
+		last := &res[len(res)-1]
+		if i.Start.After(last.End) {
+			res = append(res, i)
+		} else if i.End.After(last.End) {
+			last.End = i.End
+		}
+	}
+	return res
+}
+
+// The ISO-8601 timestamp forms accepted by parseTime, from the most precise.
+var timeLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02T15Z07:00",
+	"2006-01-02T15",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+func parseTime(s string) (time.Time, error) {
+	return parseTimeIn(s, time.UTC)
+}
+
+// parseTimeIn reads timestamps without a zone in loc.
+func parseTimeIn(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("godruid: invalid ISO-8601 time %q", s)
+}
+
+// ---------------------------------
+// ISO-8601 Period
+// ---------------------------------
+
+// Period is an ISO-8601 period such as "P1D" or "PT15M". Years, months, weeks
+// and days follow the calendar of the time they are added to, so "P1D" is not
+// always 24 hours.
+type Period struct {
+	Years   int
+	Months  int
+	Weeks   int
+	Days    int
+	Hours   int
+	Minutes int
+	Seconds int
+	Millis  int
+}
+
+func ParsePeriod(s string) (Period, error) {
+	var p Period
+	invalid := fmt.Errorf("godruid: invalid ISO-8601 period %q", s)
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return p, invalid
+	}
+	inTime := false
+	num := ""
+	for _, r := range s[1:] {
+		switch {
+		case r == 'T':
+			if inTime || num != "" {
+				return p, invalid
+			}
+			inTime = true
+		case r >= '0' && r <= '9' || r == '.':
+			num += string(r)
+		default:
+			if num == "" {
+				return p, invalid
+			}
+			if r == 'S' && inTime {
+				f, err := strconv.ParseFloat(num, 64)
+				if err != nil {
+					return p, invalid
+				}
+				p.Seconds = int(f)
+				p.Millis = int((f-float64(p.Seconds))*1000 + 0.5)
+				num = ""
+				continue
+			}
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return p, invalid
+			}
+			switch {
+			case r == 'Y' && !inTime:
+				p.Years = n
+			case r == 'M' && !inTime:
+				p.Months = n
+			case r == 'W' && !inTime:
+				p.Weeks = n
+			case r == 'D' && !inTime:
+				p.Days = n
+			case r == 'H' && inTime:
+				p.Hours = n
+			case r == 'M' && inTime:
+				p.Minutes = n
+			default:
+				return p, invalid
+			}
+			num = ""
+		}
+	}
+	if num != "" || strings.HasSuffix(s, "T") {
+		return p, invalid
+	}
+	return p, nil
+}
+
+func (p Period) String() string {
+	s := "P"
+	for _, f := range []struct {
+		n    int
+		unit string
+	}{{p.Years, "Y"}, {p.Months, "M"}, {p.Weeks, "W"}, {p.Days, "D"}} {
+		if f.n != 0 {
+			s += strconv.Itoa(f.n) + f.unit
+		}
+	}
+	if p.Hours != 0 || p.Minutes != 0 || p.Seconds != 0 || p.Millis != 0 {
+		s += "T"
+		if p.Hours != 0 {
+			s += strconv.Itoa(p.Hours) + "H"
+		}
+		if p.Minutes != 0 {
+			s += strconv.Itoa(p.Minutes) + "M"
+		}
+		if p.Millis != 0 {
+			s += strconv.FormatFloat(float64(p.Seconds)+float64(p.Millis)/1000, 'f', -1, 64) + "S"
+		} else if p.Seconds != 0 {
+			s += strconv.Itoa(p.Seconds) + "S"
+		}
+	}
+	if s == "P" {
+		s = "PT0S"
+	}
+	return s
+}
+
+// AddTo adds the period n times to t; n may be negative.
+func (p Period) AddTo(t time.Time, n int) time.Time {
+	t = t.AddDate(n*p.Years, n*p.Months, n*(7*p.Weeks+p.Days))
+	return t.Add(time.Duration(n) * (time.Duration(p.Hours)*time.Hour +
+		time.Duration(p.Minutes)*time.Minute +
+		time.Duration(p.Seconds)*time.Second +
+		time.Duration(p.Millis)*time.Millisecond))
+}
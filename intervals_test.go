@@ -0,0 +1,73 @@
+package godruid
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIntervals(t *testing.T) {
+	Convey("TestIntervals", t, func() {
+		day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+		Convey("ParseInterval", func() {
+			i, err := ParseInterval("2014-09-01T00:00/2020-01-01T00")
+			So(err, ShouldBeNil)
+			So(i, ShouldResemble, NewInterval(time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+			i, err = ParseInterval("P1D/2024-01-02")
+			So(err, ShouldBeNil)
+			So(i, ShouldResemble, NewInterval(day(1), day(2)))
+
+			i, err = ParseInterval("2024-01-01T00:00:00.000Z/P1W")
+			So(err, ShouldBeNil)
+			So(i.End, ShouldResemble, day(8))
+
+			_, err = ParseInterval("2024-01-01")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("JSON", func() {
+			b, err := json.Marshal([]Interval{IntervalFromDuration(day(1), 36*time.Hour)})
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, `["2024-01-01T00:00:00.000Z/2024-01-02T12:00:00.000Z"]`)
+
+			var is []Interval
+			So(json.Unmarshal(b, &is), ShouldBeNil)
+			So(is[0].End.Equal(day(2).Add(12*time.Hour)), ShouldBeTrue)
+		})
+
+		Convey("Period", func() {
+			p, err := ParsePeriod("P1Y2M3DT4H5M6.5S")
+			So(err, ShouldBeNil)
+			So(p, ShouldResemble, Period{Years: 1, Months: 2, Days: 3, Hours: 4, Minutes: 5, Seconds: 6, Millis: 500})
+			So(p.String(), ShouldEqual, "P1Y2M3DT4H5M6.5S")
+
+			for _, bad := range []string{"P", "PT", "1D", "P1H", "PT1D", "P1.5D"} {
+				_, err = ParsePeriod(bad)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("Align, Split and Merge", func() {
+			i := NewInterval(day(1).Add(90*time.Minute), day(1).Add(150*time.Minute))
+			So(i.Align(GranHour), ShouldResemble, NewInterval(day(1).Add(time.Hour), day(1).Add(3*time.Hour)))
+			So(i.Align(GranAll), ShouldResemble, i)
+
+			So(NewInterval(day(1), day(3)).Split(36*time.Hour), ShouldResemble, []Interval{
+				NewInterval(day(1), day(2).Add(12*time.Hour)),
+				NewInterval(day(2).Add(12*time.Hour), day(3)),
+			})
+
+			So(MergeIntervals([]Interval{
+				NewInterval(day(5), day(6)),
+				NewInterval(day(1), day(3)),
+				NewInterval(day(2), day(4)),
+				NewInterval(day(4), day(5)),
+				NewInterval(day(7), day(8)),
+			}), ShouldResemble, []Interval{NewInterval(day(1), day(6)), NewInterval(day(7), day(8))})
+		})
+	})
+}
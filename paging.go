@@ -0,0 +1,312 @@
+package godruid
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// ---------------------------------
+// TopN Pages
+// ---------------------------------
+
+// TopNIterator pages through the whole dimension of a topN query ordered by a
+// lexicographic or alphaNumeric metric, Threshold items at a time, each page
+// starting after the last value of the previous one.
+//
+//	it, err := client.IterateTopN(ctx, query, 0)
+//	if err != nil {
+//		return err
+//	}
+//	for it.Next() {
+//		item := it.Item()
+//		...
+//	}
+//	return it.Err()
+type TopNIterator struct {
+	client *Client
+	ctx    context.Context
+	query  QueryTopN
+	metric TopNMetric
+	column string
+	max    int
+
+	count int
+	page  []map[string]interface{}
+	last  bool
+	item  map[string]interface{}
+	err   error
+}
+
+// IterateTopN returns an iterator over the items of query, stopping after max
+// items if max is positive. query itself is not modified.
+func (c *Client) IterateTopN(ctx context.Context, query *QueryTopN, max int) (*TopNIterator, error) {
+	metric, ok := query.Metric.(*TopNMetric)
+	if !ok || metric.Type != "lexicographic" && metric.Type != "alphaNumeric" {
+		return nil, fmt.Errorf("godruid: topN pages need a lexicographic or alphaNumeric metric")
+	}
+	if query.Threshold <= 0 {
+		return nil, fmt.Errorf("godruid: topN pages need a positive threshold")
+	}
+	column := dimOutputName(query.Dimension)
+	if column == "" {
+		return nil, fmt.Errorf("godruid: unsupported topN dimension %T", query.Dimension)
+	}
+	return &TopNIterator{
+		client: c,
+		ctx:    ctx,
+		query:  *query,
+		metric: *metric,
+		column: column,
+		max:    max,
+	}, nil
+}
+
+// Next moves to the next item, running the query for the next page when
+// needed. It returns false once the dimension is exhausted, max items were
+// read, or an error occurred.
+func (it *TopNIterator) Next() bool {
+	if it.err != nil || it.max > 0 && it.count >= it.max {
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.last || !it.fetch() {
+			return false
+		}
+	}
+	it.item, it.page = it.page[0], it.page[1:]
+	it.count++
+	return true
+}
+
+func (it *TopNIterator) fetch() bool {
+	page := it.query
+	page.QueryResult, page.RawJSON = nil, nil
+	metric := it.metric
+	page.Metric = &metric
+	if it.max > 0 && it.max-it.count < page.Threshold {
+		page.Threshold = it.max - it.count
+	}
+
+	if it.err = it.client.QueryContext(it.ctx, &page); it.err != nil {
+		return false
+	}
+	if len(page.QueryResult) > 1 {
+		it.err = fmt.Errorf("godruid: topN pages need a single time bucket, got %d", len(page.QueryResult))
+		return false
+	}
+	if len(page.QueryResult) == 0 || len(page.QueryResult[0].Result) == 0 {
+		return false
+	}
+	it.page = page.QueryResult[0].Result
+
+	// A short page is the last one; so is one ending where it started, which
+	// would otherwise be fetched again and again.
+	stop := previousStop(it.page[len(it.page)-1][it.column])
+	it.last = len(it.page) < page.Threshold || stop == it.metric.PreviousStop
+	it.metric.PreviousStop = stop
+	return true
+}
+
+// previousStop formats a dimension value for a dimension metric's
+// previousStop. Numbers are decoded as float64, which must not go out in
+// exponent form.
+func previousStop(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// Item returns the current item.
+func (it *TopNIterator) Item() map[string]interface{} {
+	return it.item
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *TopNIterator) Err() error {
+	return it.err
+}
+
+// ---------------------------------
+// Select Pages
+// ---------------------------------
+
+// SelectIterator pages through the events of a select query, following the
+// pagingIdentifiers returned with each page, in ascending or descending order
+// as the query says.
+type SelectIterator struct {
+	client *Client
+	ctx    context.Context
+	query  QuerySelect
+	paging PagingSpec
+
+	page  []SelectEvent
+	done  bool
+	event SelectEvent
+	err   error
+}
+
+// IterateSelect returns an iterator over the events of query, which must have
+// a PagingSpec with a positive threshold; its pagingIdentifiers, if any, tell
+// where to start. query itself is not modified.
+func (c *Client) IterateSelect(ctx context.Context, query *QuerySelect) (*SelectIterator, error) {
+	if query.PagingSpec == nil || query.PagingSpec.Threshold <= 0 {
+		return nil, fmt.Errorf("godruid: select pages need a paging spec with a positive threshold")
+	}
+	paging := *query.PagingSpec
+	paging.PagingIdentifiers = make(map[string]int64, len(query.PagingSpec.PagingIdentifiers))
+	for segment, offset := range query.PagingSpec.PagingIdentifiers {
+		paging.PagingIdentifiers[segment] = offset
+	}
+	return &SelectIterator{
+		client: c,
+		ctx:    ctx,
+		query:  *query,
+		paging: paging,
+	}, nil
+}
+
+// Next moves to the next event, running the query for the next page when
+// needed. It returns false once a page comes back empty, or on error.
+func (it *SelectIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.done || !it.fetch() {
+			it.done = true
+			return false
+		}
+	}
+	it.event, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *SelectIterator) fetch() bool {
+	page := it.query
+	page.QueryResult, page.RawJSON = SelectBlob{}, nil
+	paging := it.paging
+	page.PagingSpec = &paging
+
+	if it.err = it.client.QueryContext(it.ctx, &page); it.err != nil {
+		return false
+	}
+	result := page.QueryResult.Result
+	if len(result.Events) == 0 {
+		return false
+	}
+	it.page = result.Events
+
+	// The identifiers point at the last events read; without fromNext the
+	// next page has to be asked to start one further.
+	step := int64(0)
+	if it.paging.FromNext != nil && !*it.paging.FromNext {
+		step = 1
+		if it.query.Descending {
+			step = -1
+		}
+	}
+	identifiers := make(map[string]int64, len(result.PagingIdentifiers))
+	for segment, offset := range result.PagingIdentifiers {
+		identifiers[segment] = offset + step
+	}
+	it.paging.PagingIdentifiers = identifiers
+	return true
+}
+
+// Event returns the current event.
+func (it *SelectIterator) Event() SelectEvent {
+	return it.event
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *SelectIterator) Err() error {
+	return it.err
+}
+
+// ---------------------------------
+// Scan Pages
+// ---------------------------------
+
+// ScanIterator pages through the rows of a scan query by offset, pageSize rows
+// at a time. Pages only line up if the rows come in the same order every time,
+// so the query should be ordered or read segments that don't change.
+type ScanIterator struct {
+	client   *Client
+	ctx      context.Context
+	query    QueryScan
+	pageSize int
+	end      int
+
+	page []ScanRow
+	last bool
+	row  ScanRow
+	err  error
+}
+
+// IterateScan returns an iterator over the rows of query, starting at its
+// Offset and stopping after its Limit, if any. query itself is not modified.
+func (c *Client) IterateScan(ctx context.Context, query *QueryScan, pageSize int) (*ScanIterator, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("godruid: scan pages need a positive size")
+	}
+	it := &ScanIterator{
+		client:   c,
+		ctx:      ctx,
+		query:    *query,
+		pageSize: pageSize,
+	}
+	if query.Limit > 0 {
+		it.end = query.Offset + query.Limit
+	}
+	return it, nil
+}
+
+// Next moves to the next row, running the query for the next page when
+// needed. It returns false once a page comes back short, or on error.
+func (it *ScanIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.last || !it.fetch() {
+			return false
+		}
+	}
+	it.row, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *ScanIterator) fetch() bool {
+	page := it.query
+	page.QueryResult, page.RawJSON = nil, nil
+	page.Limit = it.pageSize
+	if it.end > 0 && it.end-page.Offset < page.Limit {
+		page.Limit = it.end - page.Offset
+	}
+	if page.Limit <= 0 {
+		return false
+	}
+
+	if it.err = it.client.QueryContext(it.ctx, &page); it.err != nil {
+		return false
+	}
+	it.page = page.Rows()
+	it.query.Offset += len(it.page)
+	it.last = len(it.page) < page.Limit
+	return len(it.page) > 0
+}
+
+// Row returns the current row.
+func (it *ScanIterator) Row() ScanRow {
+	return it.row
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *ScanIterator) Err() error {
+	return it.err
+}
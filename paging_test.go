@@ -0,0 +1,215 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIterateTopN(t *testing.T) {
+	Convey("TestIterateTopN", t, func() {
+		values := []string{"a", "b", "c", "d", "e"}
+		var stops []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var q struct {
+				Threshold int
+				Metric    TopNMetric
+			}
+			json.NewDecoder(r.Body).Decode(&q)
+			stops = append(stops, q.Metric.PreviousStop)
+			var result []map[string]interface{}
+			for _, v := range values {
+				if v > q.Metric.PreviousStop && len(result) < q.Threshold {
+					result = append(result, map[string]interface{}{"page": v, "count": 1})
+				}
+			}
+			json.NewEncoder(w).Encode([]TopNItem{{Timestamp: "2024-01-01T00:00:00.000Z", Result: result}})
+		}))
+		defer server.Close()
+
+		client := &Client{Url: server.URL}
+		query := &QueryTopN{
+			DataSource:   "wikipedia",
+			Intervals:    "2024-01-01/2024-01-02",
+			Granularity:  GranAll,
+			Dimension:    DimDefault("page", "page"),
+			Metric:       TopNMetricLexicographic(""),
+			Threshold:    2,
+			Aggregations: []Aggregation{AggCount("count")},
+		}
+		read := func(max int) []string {
+			it, err := client.IterateTopN(context.Background(), query, max)
+			So(err, ShouldBeNil)
+			var res []string
+			for it.Next() {
+				res = append(res, it.Item()["page"].(string))
+			}
+			So(it.Err(), ShouldBeNil)
+			return res
+		}
+
+		Convey("pages through the whole dimension", func() {
+			So(read(0), ShouldResemble, values)
+			So(stops, ShouldResemble, []string{"", "b", "d"})
+		})
+
+		Convey("stops at max", func() {
+			So(read(3), ShouldResemble, []string{"a", "b", "c"})
+			So(stops, ShouldResemble, []string{"", "b"})
+		})
+
+		Convey("numeric dimensions, held by value", func() {
+			ids := []float64{999999, 1000000, 1000001}
+			var idStops []string
+			numeric := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var q struct{ Metric TopNMetric }
+				json.NewDecoder(r.Body).Decode(&q)
+				idStops = append(idStops, q.Metric.PreviousStop)
+				var result []map[string]interface{}
+				for i, id := range ids {
+					if len(idStops) == i+1 {
+						result = append(result, map[string]interface{}{"id": id})
+					}
+				}
+				json.NewEncoder(w).Encode([]TopNItem{{Timestamp: "2024-01-01T00:00:00.000Z", Result: result}})
+			}))
+			defer numeric.Close()
+
+			query.Dimension = Dimension{Type: "default", Dimension: "id", OutputName: "id", OutputType: OutputTypeLong}
+			query.Metric = TopNMetricAlphaNumeric("")
+			query.Threshold = 1
+			it, err := (&Client{Url: numeric.URL}).IterateTopN(context.Background(), query, 0)
+			So(err, ShouldBeNil)
+			n := 0
+			for it.Next() {
+				n++
+			}
+			So(it.Err(), ShouldBeNil)
+			So(n, ShouldEqual, 3)
+			So(idStops, ShouldResemble, []string{"", "999999", "1000000", "1000001"})
+		})
+
+		Convey("needs a lexicographic or alphaNumeric metric", func() {
+			query.Metric = TopNMetricNumeric("count")
+			_, err := client.IterateTopN(context.Background(), query, 0)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestIterateSelect(t *testing.T) {
+	Convey("TestIterateSelect", t, func() {
+		// One segment of 5 events, read from the end when descending.
+		var specs []PagingSpec
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var q struct {
+				Descending bool
+				PagingSpec *PagingSpec
+			}
+			json.NewDecoder(r.Body).Decode(&q)
+			specs = append(specs, *q.PagingSpec)
+
+			fromNext := q.PagingSpec.FromNext == nil || *q.PagingSpec.FromNext
+			step := int64(1)
+			if q.Descending {
+				step = -1
+			}
+			offset, ok := q.PagingSpec.PagingIdentifiers["seg"]
+			switch {
+			case !ok && q.Descending:
+				offset = 4
+			case !ok:
+				offset = 0
+			case fromNext:
+				offset += step
+			}
+			res := SelectResult{PagingIdentifiers: map[string]int64{}}
+			for ; offset >= 0 && offset < 5 && len(res.Events) < q.PagingSpec.Threshold; offset += step {
+				res.Events = append(res.Events, SelectEvent{SegmentId: "seg", Offset: offset})
+				res.PagingIdentifiers["seg"] = offset
+			}
+			json.NewEncoder(w).Encode([]SelectBlob{{Result: res}})
+		}))
+		defer server.Close()
+
+		client := &Client{Url: server.URL}
+		query := &QuerySelect{
+			DataSource:  "wikipedia",
+			Intervals:   "2024-01-01/2024-01-02",
+			Granularity: GranAll,
+			PagingSpec:  NewPagingSpec(2, nil),
+		}
+		read := func() []int64 {
+			it, err := client.IterateSelect(context.Background(), query)
+			So(err, ShouldBeNil)
+			var res []int64
+			for it.Next() {
+				res = append(res, it.Event().Offset)
+			}
+			So(it.Err(), ShouldBeNil)
+			return res
+		}
+
+		Convey("ascending", func() {
+			So(read(), ShouldResemble, []int64{0, 1, 2, 3, 4})
+			So(len(specs), ShouldEqual, 4)
+			So(specs[3].PagingIdentifiers, ShouldResemble, map[string]int64{"seg": 4})
+		})
+
+		Convey("descending, without fromNext", func() {
+			fromNext := false
+			query.Descending = true
+			query.PagingSpec.FromNext = &fromNext
+			So(read(), ShouldResemble, []int64{4, 3, 2, 1, 0})
+			So(specs[1].PagingIdentifiers, ShouldResemble, map[string]int64{"seg": 2})
+			So(query.PagingSpec.PagingIdentifiers, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestIterateScan(t *testing.T) {
+	Convey("TestIterateScan", t, func() {
+		var limits, offsets []int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var q struct {
+				Limit  int
+				Offset int
+			}
+			json.NewDecoder(r.Body).Decode(&q)
+			limits, offsets = append(limits, q.Limit), append(offsets, q.Offset)
+			var events []interface{}
+			for i := q.Offset; i < 7 && i < q.Offset+q.Limit; i++ {
+				events = append(events, []interface{}{i})
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"segmentId": "seg", "columns": []string{"n"}, "events": events}})
+		}))
+		defer server.Close()
+
+		client := &Client{Url: server.URL}
+		read := func(query *QueryScan) []interface{} {
+			it, err := client.IterateScan(context.Background(), query, 3)
+			So(err, ShouldBeNil)
+			var res []interface{}
+			for it.Next() {
+				res = append(res, it.Row().Get("n"))
+			}
+			So(it.Err(), ShouldBeNil)
+			return res
+		}
+
+		Convey("reads every page", func() {
+			So(read(&QueryScan{ResultFormat: ScanResultFormatCompactedList}), ShouldResemble, []interface{}{0.0, 1.0, 2.0, 3.0, 4.0, 5.0, 6.0})
+			So(offsets, ShouldResemble, []int{0, 3, 6})
+		})
+
+		Convey("keeps to the query's offset and limit", func() {
+			So(read(&QueryScan{Offset: 1, Limit: 4}), ShouldResemble, []interface{}{1.0, 2.0, 3.0, 4.0})
+			So(offsets, ShouldResemble, []int{1, 4})
+			So(limits, ShouldResemble, []int{3, 1})
+		})
+	})
+}
@@ -0,0 +1,164 @@
+package godruid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlanError is returned by PlanMetrics when the requested metrics can't be
+// resolved from the catalogue.
+type PlanError struct {
+	// Missing lists the unknown names (Refer) and who asked for them (Name,
+	// empty for the requested metrics themselves).
+	Missing []AggRefer
+	// Cycle, if not empty, is a chain of post aggregations that refer back to
+	// the first one.
+	Cycle []string
+}
+
+func (e *PlanError) Error() string {
+	var msgs []string
+	for _, m := range e.Missing {
+		if m.Name == "" {
+			msgs = append(msgs, fmt.Sprintf("unknown metric %q", m.Refer))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("unknown metric %q referenced by %q", m.Refer, m.Name))
+		}
+	}
+	if len(e.Cycle) > 0 {
+		msgs = append(msgs, "cyclic post aggregations: "+strings.Join(e.Cycle, " -> "))
+	}
+	return "godruid: " + strings.Join(msgs, "; ")
+}
+
+// PlanMetrics picks, out of a catalogue of aggregations and post aggregations,
+// the ones needed to compute the named metrics and everything they depend on.
+// Post aggregations are ordered so that each one comes after the post
+// aggregations it refers to, as Druid requires; aggregations are in the order
+// they were first needed.
+func PlanMetrics(metrics []string, aggs []Aggregation, postAggs []PostAggregation) ([]Aggregation, []PostAggregation, error) {
+	p := &planner{
+		aggs:     make(map[string]Aggregation),
+		postAggs: make(map[string]PostAggregation),
+		state:    make(map[string]int),
+	}
+	for _, agg := range aggs {
+		name := agg.Name
+		if agg.Type == "filtered" && agg.Aggregator != nil {
+			name = agg.Aggregator.Name
+		}
+		p.aggs[name] = agg
+	}
+	for _, pa := range postAggs {
+		if _, ok := p.aggs[pa.Name]; ok {
+			return nil, nil, fmt.Errorf("godruid: %q is both an aggregation and a post aggregation", pa.Name)
+		}
+		p.postAggs[pa.Name] = pa
+	}
+
+	for _, metric := range metrics {
+		p.visit("", metric)
+		if len(p.err.Cycle) > 0 {
+			break
+		}
+	}
+	if len(p.err.Missing) > 0 || len(p.err.Cycle) > 0 {
+		return nil, nil, &p.err
+	}
+	return p.outAggs, p.outPostAggs, nil
+}
+
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+type planner struct {
+	aggs     map[string]Aggregation
+	postAggs map[string]PostAggregation
+
+	state map[string]int
+	stack []string
+	err   PlanError
+
+	outAggs     []Aggregation
+	outPostAggs []PostAggregation
+}
+
+// visit adds name, depth first, after everything it depends on.
+func (p *planner) visit(from, name string) {
+	switch p.state[name] {
+	case visited:
+		return
+	case visiting:
+		for i, n := range p.stack {
+			if n == name {
+				p.err.Cycle = append(append([]string{}, p.stack[i:]...), name)
+				break
+			}
+		}
+		return
+	}
+
+	if agg, ok := p.aggs[name]; ok {
+		p.state[name] = visited
+		p.outAggs = append(p.outAggs, agg)
+		return
+	}
+	pa, ok := p.postAggs[name]
+	if !ok {
+		p.state[name] = visited
+		p.err.Missing = append(p.err.Missing, AggRefer{from, name})
+		return
+	}
+
+	p.state[name] = visiting
+	p.stack = append(p.stack, name)
+	for _, dep := range postAggDeps(pa) {
+		p.visit(name, dep)
+		if len(p.err.Cycle) > 0 {
+			return
+		}
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+	p.state[name] = visited
+	p.outPostAggs = append(p.outPostAggs, pa)
+}
+
+// postAggDeps returns the names pa reads, leaving out the post aggregations
+// nested inside it, which come with it.
+func postAggDeps(pa PostAggregation) []string {
+	inline := make(map[string]bool)
+	nestedNames(pa, inline)
+	refers := pa.GetReferAggs()
+	var deps []string
+	seen := make(map[string]bool)
+	for _, r := range refers {
+		if r.Refer != "" && !inline[r.Refer] && !seen[r.Refer] {
+			seen[r.Refer] = true
+			deps = append(deps, r.Refer)
+		}
+	}
+	return deps
+}
+
+// nestedNames adds to names those of the post aggregations nested inside pa
+// that compute something, but not pa's own. Accessors may carry a name too,
+// but what they read still has to come from elsewhere.
+func nestedNames(pa PostAggregation, names map[string]bool) {
+	nested := pa.Fields
+	if pa.Field != nil {
+		nested = append([]PostAggregation{*pa.Field}, nested...)
+	}
+	for _, field := range nested {
+		switch field.Type {
+		case "fieldAccess", "finalizingFieldAccess", "hyperUniqueCardinality":
+			continue
+		}
+		if field.Name != "" {
+			names[field.Name] = true
+		}
+		nestedNames(field, names)
+	}
+}
@@ -0,0 +1,84 @@
+package godruid
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPlanMetrics(t *testing.T) {
+	Convey("TestPlanMetrics", t, func() {
+		Convey("GetReferAggs on a bare accessor", func() {
+			So(PostAggFieldAccessor("rows").GetReferAggs(), ShouldResemble, []AggRefer{{"", "rows"}})
+			So(PostAggFieldHyperUnique("users").GetReferAggs(), ShouldResemble, []AggRefer{{"", "users"}})
+			So(PostAggFinalizingFieldAccessor("users").GetReferAggs(), ShouldResemble, []AggRefer{{"", "users"}})
+		})
+
+		aggs := []Aggregation{
+			AggCount("rows"),
+			AggLongSum("bytes", "bytes"),
+			AggLongSum("unused", "unused"),
+			AggFiltered(FilterSelector("country", "US"), &Aggregation{Type: "count", Name: "usRows"}),
+		}
+		postAggs := []PostAggregation{
+			PostAggArithmetic("usShare", "/", []PostAggregation{
+				PostAggFieldAccessor("usRows"),
+				PostAggFieldAccessor("rows"),
+			}),
+			PostAggExpression("usPercent", "usShare * 100"),
+			PostAggArithmetic("avgBytes", "/", []PostAggregation{
+				PostAggFieldAccessor("bytes"),
+				PostAggFieldAccessor("rows"),
+			}),
+		}
+
+		Convey("picks the dependencies, in order", func() {
+			a, pa, err := PlanMetrics([]string{"usPercent", "bytes"}, aggs, postAggs)
+			So(err, ShouldBeNil)
+			So(a, ShouldResemble, []Aggregation{aggs[3], aggs[0], aggs[1]})
+			So(pa, ShouldResemble, []PostAggregation{postAggs[0], postAggs[1]})
+		})
+
+		Convey("keeps what named accessors read", func() {
+			tot := PostAggFieldAccessor("tot")
+			tot.Name = "tot"
+			rows := PostAggFinalizingFieldAccessor("rows")
+			rows.Name = "rows"
+			avg := PostAggArithmetic("avg", "/", []PostAggregation{tot, rows})
+			a, pa, err := PlanMetrics([]string{"avg"}, []Aggregation{AggLongSum("tot", "tot"), AggCount("rows")}, []PostAggregation{avg})
+			So(err, ShouldBeNil)
+			So(a, ShouldResemble, []Aggregation{AggLongSum("tot", "tot"), AggCount("rows")})
+			So(pa, ShouldResemble, []PostAggregation{avg})
+
+			// A nested post aggregation that computes something comes with its parent.
+			ratio := PostAggArithmetic("ratio", "/", []PostAggregation{
+				PostAggArithmetic("sum", "+", []PostAggregation{tot, rows}),
+				rows,
+			})
+			a, pa, err = PlanMetrics([]string{"ratio"}, []Aggregation{AggCount("rows"), AggLongSum("tot", "tot")}, []PostAggregation{ratio})
+			So(err, ShouldBeNil)
+			So(a, ShouldResemble, []Aggregation{AggLongSum("tot", "tot"), AggCount("rows")})
+			So(pa, ShouldResemble, []PostAggregation{ratio})
+		})
+
+		Convey("reports missing references", func() {
+			_, _, err := PlanMetrics([]string{"avgBytes", "nope"}, aggs[2:], postAggs)
+			So(err, ShouldNotBeNil)
+			So(err.(*PlanError).Missing, ShouldResemble, []AggRefer{{"avgBytes", "bytes"}, {"avgBytes", "rows"}, {"", "nope"}})
+		})
+
+		Convey("detects cycles", func() {
+			cyclic := []PostAggregation{
+				PostAggExpression("a", "b + 1"),
+				PostAggArithmetic("b", "*", []PostAggregation{PostAggFieldAccessor("a"), PostAggConstant("two", 2)}),
+			}
+			_, _, err := PlanMetrics([]string{"a"}, nil, cyclic)
+			So(err, ShouldNotBeNil)
+			So(err.(*PlanError).Cycle, ShouldResemble, []string{"a", "b", "a"})
+
+			_, _, err = PlanMetrics([]string{"a"}, nil, []PostAggregation{PostAggExpression("a", "a + 1")})
+			So(err, ShouldNotBeNil)
+			So(err.(*PlanError).Cycle, ShouldResemble, []string{"a", "a"})
+		})
+	})
+}
@@ -2,6 +2,7 @@ package godruid
 
 import (
 	"encoding/json"
+	"unicode"
 )
 
 type PostAggregation struct {
@@ -13,8 +14,28 @@ type PostAggregation struct {
 	FieldName  string            `json:"fieldName,omitempty"`
 	FieldNames []string          `json:"fieldNames,omitempty"`
 	Function   string            `json:"function,omitempty"`
+	Expression string            `json:"expression,omitempty"`
+	Ordering   string            `json:"ordering,omitempty"`
+
+	// DataSketches extension.
+	Field       *PostAggregation `json:"field,omitempty"`
+	Func        string           `json:"func,omitempty"`
+	Size        int              `json:"size,omitempty"`
+	Round       bool             `json:"round,omitempty"`
+	LgK         int              `json:"lgK,omitempty"`
+	TgtHllType  string           `json:"tgtHllType,omitempty"`
+	Fraction    *float64         `json:"fraction,omitempty"`
+	Fractions   []float64        `json:"fractions,omitempty"`
+	SplitPoints []float64        `json:"splitPoints,omitempty"`
+	NumBins     int              `json:"numBins,omitempty"`
 }
 
+// Orderings of the arithmetic and expression post aggregations.
+const (
+	// NaN, then Infinity, then the other numbers.
+	PostAggOrderingNumericFirst = "numericFirst"
+)
+
 // The agg reference.
 type AggRefer struct {
 	Name  string
@@ -25,25 +46,45 @@ type AggRefer struct {
 // It could be helpful while automatically filling the aggregations or post aggregations base on this.
 func (pa PostAggregation) GetReferAggs(parentName ...string) (refers []AggRefer) {
 	switch pa.Type {
-	case "arithmetic":
+	case "arithmetic",
+		"doubleGreatest", "doubleLeast", "longGreatest", "longLeast",
+		"thetaSketchEstimate", "thetaSketchSetOp",
+		"HLLSketchEstimate", "HLLSketchUnion",
+		"quantilesDoublesSketchToQuantile", "quantilesDoublesSketchToQuantiles",
+		"quantilesDoublesSketchToHistogram", "quantilesDoublesSketchToCDF":
 		if len(parentName) != 0 {
 			refers = append(refers, AggRefer{parentName[0], pa.Name})
 		} else {
 			refers = append(refers, AggRefer{pa.Name, ""})
 		}
+		if pa.Field != nil {
+			refers = append(refers, pa.Field.GetReferAggs(pa.Name)...)
+		}
 		for _, spa := range pa.Fields {
 			refers = append(refers, spa.GetReferAggs(pa.Name)...)
 		}
-	case "fieldAccess":
-		refers = append(refers, AggRefer{parentName[0], pa.FieldName})
+	case "expression":
+		if len(parentName) != 0 {
+			refers = append(refers, AggRefer{parentName[0], pa.Name})
+		} else {
+			refers = append(refers, AggRefer{pa.Name, ""})
+		}
+		for _, ident := range expressionIdentifiers(pa.Expression) {
+			refers = append(refers, AggRefer{pa.Name, ident})
+		}
+	case "fieldAccess", "finalizingFieldAccess", "hyperUniqueCardinality":
+		// A bare accessor has no parent; it then refers on its own behalf.
+		name := pa.Name
+		if len(parentName) != 0 {
+			name = parentName[0]
+		}
+		refers = append(refers, AggRefer{name, pa.FieldName})
 	case "constant":
 		// no need refers.
 	case "javascript":
 		for _, f := range pa.FieldNames {
 			refers = append(refers, AggRefer{pa.Name, f})
 		}
-	case "hyperUniqueCardinality":
-		refers = append(refers, AggRefer{parentName[0], pa.FieldName})
 	}
 	return
 }
@@ -54,12 +95,17 @@ func PostAggRawJson(rawJson string) PostAggregation {
 	return *pa
 }
 
-func PostAggArithmetic(name, fn string, fields []PostAggregation) PostAggregation {
+func PostAggArithmetic(name, fn string, fields []PostAggregation, ordering ...string) PostAggregation {
+	var realOrdering string
+	if len(ordering) != 0 {
+		realOrdering = ordering[0]
+	}
 	return PostAggregation{
-		Type:   "arithmetic",
-		Name:   name,
-		Fn:     fn,
-		Fields: fields,
+		Type:     "arithmetic",
+		Name:     name,
+		Fn:       fn,
+		Fields:   fields,
+		Ordering: realOrdering,
 	}
 }
 
@@ -70,6 +116,13 @@ func PostAggFieldAccessor(fieldName string) PostAggregation {
 	}
 }
 
+func PostAggFinalizingFieldAccessor(fieldName string) PostAggregation {
+	return PostAggregation{
+		Type:      "finalizingFieldAccess",
+		FieldName: fieldName,
+	}
+}
+
 func PostAggConstant(name string, value interface{}) PostAggregation {
 	return PostAggregation{
 		Type:  "constant",
@@ -93,3 +146,191 @@ func PostAggFieldHyperUnique(fieldName string) PostAggregation {
 		FieldName: fieldName,
 	}
 }
+
+// PostAggExpression computes a Druid expression over the aggregations and the
+// other post aggregations, e.g. "revenue / count".
+func PostAggExpression(name, expression string, ordering ...string) PostAggregation {
+	var realOrdering string
+	if len(ordering) != 0 {
+		realOrdering = ordering[0]
+	}
+	return PostAggregation{
+		Type:       "expression",
+		Name:       name,
+		Expression: expression,
+		Ordering:   realOrdering,
+	}
+}
+
+func PostAggDoubleGreatest(name string, fields []PostAggregation) PostAggregation {
+	return PostAggregation{
+		Type:   "doubleGreatest",
+		Name:   name,
+		Fields: fields,
+	}
+}
+
+func PostAggDoubleLeast(name string, fields []PostAggregation) PostAggregation {
+	return PostAggregation{
+		Type:   "doubleLeast",
+		Name:   name,
+		Fields: fields,
+	}
+}
+
+func PostAggLongGreatest(name string, fields []PostAggregation) PostAggregation {
+	return PostAggregation{
+		Type:   "longGreatest",
+		Name:   name,
+		Fields: fields,
+	}
+}
+
+func PostAggLongLeast(name string, fields []PostAggregation) PostAggregation {
+	return PostAggregation{
+		Type:   "longLeast",
+		Name:   name,
+		Fields: fields,
+	}
+}
+
+// expressionIdentifiers returns the columns a Druid expression reads, in order
+// of first appearance: bare identifiers that are not function names, and
+// "double quoted" identifiers. 'Single quoted' strings are literals.
+func expressionIdentifiers(expr string) []string {
+	var idents []string
+	seen := make(map[string]bool)
+	add := func(ident string) {
+		if ident != "" && ident != "null" && !seen[ident] {
+			seen[ident] = true
+			idents = append(idents, ident)
+		}
+	}
+	isStart := func(c byte) bool { return c == '_' || c == '$' || unicode.IsLetter(rune(c)) }
+	isPart := func(c byte) bool { return isStart(c) || unicode.IsDigit(rune(c)) }
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(expr) && expr[j] != c {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j > len(expr) {
+				j = len(expr)
+			}
+			if c == '"' {
+				add(expr[i+1 : j])
+			}
+			i = j + 1
+		case isStart(c):
+			j := i
+			for j < len(expr) && isPart(expr[j]) {
+				j++
+			}
+			k := j
+			for k < len(expr) && expr[k] == ' ' {
+				k++
+			}
+			if k == len(expr) || expr[k] != '(' {
+				add(expr[i:j])
+			}
+			i = j
+		case unicode.IsDigit(rune(c)):
+			// Skip numbers, exponents included, so 1e3 isn't taken for an identifier.
+			for i < len(expr) && (isPart(expr[i]) || expr[i] == '.') {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return idents
+}
+
+// ---------------------------------
+// DataSketches Post Aggregations
+// ---------------------------------
+
+// The field of the sketch post aggregations is usually a PostAggFieldAccessor
+// of a sketch aggregation, or another sketch post aggregation.
+
+func PostAggThetaSketchEstimate(name string, field PostAggregation) PostAggregation {
+	return PostAggregation{
+		Type:  "thetaSketchEstimate",
+		Name:  name,
+		Field: &field,
+	}
+}
+
+// fn is one of "UNION", "INTERSECT" or "NOT".
+func PostAggThetaSketchSetOp(name, fn string, size int, fields []PostAggregation) PostAggregation {
+	return PostAggregation{
+		Type:   "thetaSketchSetOp",
+		Name:   name,
+		Func:   fn,
+		Size:   size,
+		Fields: fields,
+	}
+}
+
+func PostAggHLLSketchEstimate(name string, field PostAggregation, round bool) PostAggregation {
+	return PostAggregation{
+		Type:  "HLLSketchEstimate",
+		Name:  name,
+		Field: &field,
+		Round: round,
+	}
+}
+
+func PostAggHLLSketchUnion(name string, fields []PostAggregation, lgK int, tgtHllType string) PostAggregation {
+	return PostAggregation{
+		Type:       "HLLSketchUnion",
+		Name:       name,
+		Fields:     fields,
+		LgK:        lgK,
+		TgtHllType: tgtHllType,
+	}
+}
+
+func PostAggQuantilesDoublesSketchToQuantile(name string, field PostAggregation, fraction float64) PostAggregation {
+	return PostAggregation{
+		Type:     "quantilesDoublesSketchToQuantile",
+		Name:     name,
+		Field:    &field,
+		Fraction: &fraction,
+	}
+}
+
+func PostAggQuantilesDoublesSketchToQuantiles(name string, field PostAggregation, fractions []float64) PostAggregation {
+	return PostAggregation{
+		Type:      "quantilesDoublesSketchToQuantiles",
+		Name:      name,
+		Field:     &field,
+		Fractions: fractions,
+	}
+}
+
+// Either splitPoints or numBins (evenly spaced bins) is used.
+func PostAggQuantilesDoublesSketchToHistogram(name string, field PostAggregation, splitPoints []float64, numBins int) PostAggregation {
+	return PostAggregation{
+		Type:        "quantilesDoublesSketchToHistogram",
+		Name:        name,
+		Field:       &field,
+		SplitPoints: splitPoints,
+		NumBins:     numBins,
+	}
+}
+
+func PostAggQuantilesDoublesSketchToCDF(name string, field PostAggregation, splitPoints []float64) PostAggregation {
+	return PostAggregation{
+		Type:        "quantilesDoublesSketchToCDF",
+		Name:        name,
+		Field:       &field,
+		SplitPoints: splitPoints,
+	}
+}
@@ -0,0 +1,92 @@
+package godruid
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPostAggregations(t *testing.T) {
+	Convey("TestPostAggregations", t, func() {
+		users := PostAggFieldAccessor("users")
+		latency := PostAggFieldAccessor("latency")
+
+		Convey("expression, arithmetic, greatest and least", func() {
+			fields := []PostAggregation{PostAggFieldAccessor("min"), PostAggFinalizingFieldAccessor("max")}
+			cases := []struct {
+				postAgg PostAggregation
+				json    string
+			}{
+				{PostAggExpression("share", "usRows / rows"), `{"type":"expression","name":"share","expression":"usRows / rows"}`},
+				{PostAggExpression("share", "usRows / rows", PostAggOrderingNumericFirst),
+					`{"type":"expression","name":"share","expression":"usRows / rows","ordering":"numericFirst"}`},
+				{PostAggArithmetic("avg", "/", []PostAggregation{PostAggFieldAccessor("tot"), PostAggConstant("two", 2)}, PostAggOrderingNumericFirst),
+					`{"type":"arithmetic","name":"avg","fn":"/","fields":[{"type":"fieldAccess","fieldName":"tot"},{"type":"constant","name":"two","value":2}],"ordering":"numericFirst"}`},
+				{PostAggFinalizingFieldAccessor("users"), `{"type":"finalizingFieldAccess","fieldName":"users"}`},
+				{PostAggDoubleGreatest("top", fields),
+					`{"type":"doubleGreatest","name":"top","fields":[{"type":"fieldAccess","fieldName":"min"},{"type":"finalizingFieldAccess","fieldName":"max"}]}`},
+				{PostAggDoubleLeast("bottom", fields),
+					`{"type":"doubleLeast","name":"bottom","fields":[{"type":"fieldAccess","fieldName":"min"},{"type":"finalizingFieldAccess","fieldName":"max"}]}`},
+				{PostAggLongGreatest("top", fields),
+					`{"type":"longGreatest","name":"top","fields":[{"type":"fieldAccess","fieldName":"min"},{"type":"finalizingFieldAccess","fieldName":"max"}]}`},
+				{PostAggLongLeast("bottom", fields),
+					`{"type":"longLeast","name":"bottom","fields":[{"type":"fieldAccess","fieldName":"min"},{"type":"finalizingFieldAccess","fieldName":"max"}]}`},
+			}
+			for _, c := range cases {
+				data, err := json.Marshal(c.postAgg)
+				So(err, ShouldBeNil)
+				So(string(data), ShouldEqual, c.json)
+			}
+
+			for _, pa := range []PostAggregation{PostAggDoubleGreatest("m", fields), PostAggDoubleLeast("m", fields),
+				PostAggLongGreatest("m", fields), PostAggLongLeast("m", fields)} {
+				So(pa.GetReferAggs(), ShouldResemble, []AggRefer{{"m", ""}, {"m", "min"}, {"m", "max"}})
+			}
+			So(PostAggExpression("share", `usRows / "rows"`).GetReferAggs(), ShouldResemble, []AggRefer{{"share", ""}, {"share", "usRows"}, {"share", "rows"}})
+		})
+
+		Convey("DataSketches", func() {
+			cases := []struct {
+				postAgg PostAggregation
+				json    string
+			}{
+				{PostAggThetaSketchEstimate("uniqueUsers", users),
+					`{"type":"thetaSketchEstimate","name":"uniqueUsers","field":{"type":"fieldAccess","fieldName":"users"}}`},
+				{PostAggThetaSketchSetOp("both", "INTERSECT", 16384, []PostAggregation{users, PostAggFieldAccessor("buyers")}),
+					`{"type":"thetaSketchSetOp","name":"both","fields":[{"type":"fieldAccess","fieldName":"users"},{"type":"fieldAccess","fieldName":"buyers"}],"func":"INTERSECT","size":16384}`},
+				{PostAggHLLSketchEstimate("uniqueUsers", users, true),
+					`{"type":"HLLSketchEstimate","name":"uniqueUsers","field":{"type":"fieldAccess","fieldName":"users"},"round":true}`},
+				{PostAggHLLSketchUnion("allUsers", []PostAggregation{users, PostAggFieldAccessor("buyers")}, 12, "HLL_8"),
+					`{"type":"HLLSketchUnion","name":"allUsers","fields":[{"type":"fieldAccess","fieldName":"users"},{"type":"fieldAccess","fieldName":"buyers"}],"lgK":12,"tgtHllType":"HLL_8"}`},
+				{PostAggQuantilesDoublesSketchToQuantile("p0", latency, 0),
+					`{"type":"quantilesDoublesSketchToQuantile","name":"p0","field":{"type":"fieldAccess","fieldName":"latency"},"fraction":0}`},
+				{PostAggQuantilesDoublesSketchToQuantiles("ps", latency, []float64{0.5, 0.99}),
+					`{"type":"quantilesDoublesSketchToQuantiles","name":"ps","field":{"type":"fieldAccess","fieldName":"latency"},"fractions":[0.5,0.99]}`},
+				{PostAggQuantilesDoublesSketchToHistogram("histogram", latency, []float64{10, 100}, 0),
+					`{"type":"quantilesDoublesSketchToHistogram","name":"histogram","field":{"type":"fieldAccess","fieldName":"latency"},"splitPoints":[10,100]}`},
+				{PostAggQuantilesDoublesSketchToCDF("cdf", latency, []float64{10}),
+					`{"type":"quantilesDoublesSketchToCDF","name":"cdf","field":{"type":"fieldAccess","fieldName":"latency"},"splitPoints":[10]}`},
+			}
+			for _, c := range cases {
+				data, err := json.Marshal(c.postAgg)
+				So(err, ShouldBeNil)
+				So(string(data), ShouldEqual, c.json)
+			}
+		})
+
+		Convey("GetReferAggs through nested Field and Fields", func() {
+			share := PostAggHLLSketchEstimate("share", PostAggHLLSketchUnion("allUsers", []PostAggregation{
+				users,
+				PostAggThetaSketchEstimate("buyers", PostAggFieldAccessor("buyerSketch")),
+			}, 0, ""), false)
+			So(share.GetReferAggs(), ShouldResemble, []AggRefer{
+				{"share", ""},
+				{"share", "allUsers"},
+				{"allUsers", "users"},
+				{"allUsers", "buyers"},
+				{"buyers", "buyerSketch"},
+			})
+		})
+	})
+}
@@ -1,7 +1,12 @@
 package godruid
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
 )
 
 // Check http://druid.io/docs/0.6.154/Querying.html#query-operators for detail description.
@@ -11,6 +16,13 @@ type Query interface {
 	setup()
 	onResponse(content []byte) error
 	GetRawJSON() []byte
+	// GetQueryID returns the queryId set in the query's Context or, without
+	// one, the id the query was last sent with, which is also kept in QueryID.
+	GetQueryID() string
+	// setQueryID picks the id for the next request and records it in QueryID.
+	// Without one in the Context, each request gets a new id that restore
+	// takes back off.
+	setQueryID() (restore func())
 }
 
 type QueryType string
@@ -24,27 +36,91 @@ const (
 	TIMEBOUNDARY    QueryType = "timeBoundary"
 	SELECT          QueryType = "select"
 	SCAN            QueryType = "scan"
+
+	DATASOURCEMETADATA QueryType = "dataSourceMetadata"
 )
 
+// ---------------------------------
+// Query Context
+// ---------------------------------
+
+// The queryId is what the broker knows a running query by, so it's what we
+// pass to DELETE /druid/v2/{queryId} when cancelling it.
+func queryID(context map[string]interface{}) string {
+	id, _ := context["queryId"].(string)
+	return id
+}
+
+func getQueryID(context map[string]interface{}, sent string) string {
+	return contextID(context, "queryId", sent)
+}
+
+func withQueryID(context *map[string]interface{}, sent *string) (restore func()) {
+	return withContextID(context, "queryId", sent)
+}
+
+// contextID returns the id under key in context, or sent without one.
+func contextID(context map[string]interface{}, key, sent string) string {
+	if id, _ := context[key].(string); id != "" {
+		return id
+	}
+	return sent
+}
+
+// withContextID records in *sent the id found under key in *context, or a new
+// one that only a copy of *context gets.
+func withContextID(context *map[string]interface{}, key string, sent *string) (restore func()) {
+	if id, _ := (*context)[key].(string); id != "" {
+		*sent = id
+		return func() {}
+	}
+	*sent = newQueryID()
+	return withContextValue(context, key, *sent)
+}
+
+// withContextValue points *context at a copy of it holding key, so that the
+// caller's map is never written to, until restore puts the original back.
+func withContextValue(context *map[string]interface{}, key string, value interface{}) (restore func()) {
+	orig := *context
+	c := make(map[string]interface{}, len(orig)+1)
+	for k, v := range orig {
+		c[k] = v
+	}
+	c[key] = value
+	*context = c
+	return func() { *context = orig }
+}
+
+// newQueryID returns a random (version 4) UUID.
+func newQueryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
 // ---------------------------------
 // GroupBy Query
 // ---------------------------------
 
 type QueryGroupBy struct {
 	QueryType        QueryType              `json:"queryType"`
-	DataSource       string                 `json:"dataSource"`
+	DataSource       DataSource             `json:"dataSource"`
 	Dimensions       []DimSpec              `json:"dimensions"`
-	Granularity      Granlarity             `json:"granularity"`
+	Granularity      Granularity            `json:"granularity"`
 	LimitSpec        *Limit                 `json:"limitSpec,omitempty"`
 	Having           *Having                `json:"having,omitempty"`
 	Filter           *Filter                `json:"filter,omitempty"`
 	Aggregations     []Aggregation          `json:"aggregations"`
 	PostAggregations []PostAggregation      `json:"postAggregations,omitempty"`
 	Intervals        Intervals              `json:"intervals"`
+	VirtualColumns   []VirtualColumn        `json:"virtualColumns,omitempty"`
 	Context          map[string]interface{} `json:"context,omitempty"`
 
 	QueryResult []GroupbyItem `json:"-"`
 	RawJSON     []byte
+	QueryID     string `json:"-"`
 }
 
 type GroupbyItem struct {
@@ -55,6 +131,8 @@ type GroupbyItem struct {
 
 func (q *QueryGroupBy) setup()             { q.QueryType = GROUPBY }
 func (q *QueryGroupBy) GetRawJSON() []byte { return q.RawJSON }
+func (q *QueryGroupBy) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QueryGroupBy) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
 func (q *QueryGroupBy) onResponse(content []byte) error {
 	res := new([]GroupbyItem)
 	err := json.Unmarshal(content, res)
@@ -72,10 +150,11 @@ func (q *QueryGroupBy) onResponse(content []byte) error {
 
 type QuerySearch struct {
 	QueryType        QueryType              `json:"queryType"`
-	DataSource       string                 `json:"dataSource"`
-	Granularity      Granlarity             `json:"granularity"`
+	DataSource       DataSource             `json:"dataSource"`
+	Granularity      Granularity            `json:"granularity"`
 	Filter           *Filter                `json:"filter,omitempty"`
 	Intervals        Intervals              `json:"intervals"`
+	VirtualColumns   []VirtualColumn        `json:"virtualColumns,omitempty"`
 	SearchDimensions []string               `json:"searchDimensions,omitempty"`
 	Query            *SearchQuery           `json:"query"`
 	Sort             *SearchSort            `json:"sort"`
@@ -83,6 +162,7 @@ type QuerySearch struct {
 
 	QueryResult []SearchItem `json:"-"`
 	RawJSON     []byte
+	QueryID     string `json:"-"`
 }
 
 type SearchItem struct {
@@ -97,6 +177,8 @@ type DimValue struct {
 
 func (q *QuerySearch) setup()             { q.QueryType = SEARCH }
 func (q *QuerySearch) GetRawJSON() []byte { return q.RawJSON }
+func (q *QuerySearch) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QuerySearch) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
 func (q *QuerySearch) onResponse(content []byte) error {
 	res := new([]SearchItem)
 	err := json.Unmarshal(content, res)
@@ -114,7 +196,7 @@ func (q *QuerySearch) onResponse(content []byte) error {
 
 type QuerySegmentMetadata struct {
 	QueryType  QueryType              `json:"queryType"`
-	DataSource string                 `json:"dataSource"`
+	DataSource DataSource             `json:"dataSource"`
 	Intervals  Intervals              `json:"intervals"`
 	ToInclude  *ToInclude             `json:"toInclude,omitempty"`
 	Merge      interface{}            `json:"merge,omitempty"`
@@ -122,6 +204,7 @@ type QuerySegmentMetadata struct {
 
 	QueryResult []SegmentMetaData `json:"-"`
 	RawJSON     []byte
+	QueryID     string `json:"-"`
 }
 
 type SegmentMetaData struct {
@@ -138,6 +221,8 @@ type ColumnItem struct {
 
 func (q *QuerySegmentMetadata) setup()             { q.QueryType = "segmentMetadata" }
 func (q *QuerySegmentMetadata) GetRawJSON() []byte { return q.RawJSON }
+func (q *QuerySegmentMetadata) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QuerySegmentMetadata) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
 func (q *QuerySegmentMetadata) onResponse(content []byte) error {
 	res := new([]SegmentMetaData)
 	err := json.Unmarshal(content, res)
@@ -155,12 +240,13 @@ func (q *QuerySegmentMetadata) onResponse(content []byte) error {
 
 type QueryTimeBoundary struct {
 	QueryType  QueryType              `json:"queryType"`
-	DataSource string                 `json:"dataSource"`
-	Bound      string                 `json:"bound,omitempty"`
+	DataSource DataSource             `json:"dataSource"`
+	Bound      Bound                  `json:"bound,omitempty"`
 	Context    map[string]interface{} `json:"context,omitempty"`
 
 	QueryResult []TimeBoundaryItem `json:"-"`
 	RawJSON     []byte
+	QueryID     string `json:"-"`
 }
 
 type TimeBoundaryItem struct {
@@ -168,13 +254,23 @@ type TimeBoundaryItem struct {
 	Result    TimeBoundary `json:"result"`
 }
 
+// With Bound set, only the asked for side is filled in; the other one is zero.
 type TimeBoundary struct {
-	MinTime string `json:"minTime"`
-	MaxTime string `json:"minTime"`
+	MinTime time.Time `json:"minTime"`
+	MaxTime time.Time `json:"maxTime"`
 }
 
+type Bound string
+
+const (
+	BoundMaxTime Bound = "maxTime"
+	BoundMinTime Bound = "minTime"
+)
+
 func (q *QueryTimeBoundary) setup()             { q.QueryType = TIMEBOUNDARY }
 func (q *QueryTimeBoundary) GetRawJSON() []byte { return q.RawJSON }
+func (q *QueryTimeBoundary) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QueryTimeBoundary) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
 func (q *QueryTimeBoundary) onResponse(content []byte) error {
 	res := new([]TimeBoundaryItem)
 	err := json.Unmarshal(content, res)
@@ -186,22 +282,105 @@ func (q *QueryTimeBoundary) onResponse(content []byte) error {
 	return nil
 }
 
+// Interval returns the interval spanned by the result: from minTime up to and
+// including maxTime. It's false when the datasource had no data, or when only
+// one bound was asked for.
+func (q *QueryTimeBoundary) Interval() (Interval, bool) {
+	if len(q.QueryResult) == 0 {
+		return Interval{}, false
+	}
+	res := q.QueryResult[0].Result
+	if res.MinTime.IsZero() || res.MaxTime.IsZero() {
+		return Interval{}, false
+	}
+	// Druid intervals exclude their end, so step one millisecond past maxTime.
+	return Interval{Start: res.MinTime, End: res.MaxTime.Add(time.Millisecond)}, true
+}
+
+// DataSourceInterval runs a timeBoundary query and returns the interval that
+// holds all of dataSource's data, ready to be used as a query's Intervals.
+func (c *Client) DataSourceInterval(ctx context.Context, dataSource string) (Interval, error) {
+	query := &QueryTimeBoundary{DataSource: dataSource}
+	if err := c.QueryContext(ctx, query); err != nil {
+		return Interval{}, err
+	}
+	interval, ok := query.Interval()
+	if !ok {
+		return Interval{}, fmt.Errorf("godruid: datasource %s has no data", dataSource)
+	}
+	return interval, nil
+}
+
+// ---------------------------------
+// DataSourceMetadata Query
+// ---------------------------------
+
+type QueryDataSourceMetadata struct {
+	QueryType  QueryType              `json:"queryType"`
+	DataSource DataSource             `json:"dataSource"`
+	Context    map[string]interface{} `json:"context,omitempty"`
+
+	QueryResult []DataSourceMetadataItem `json:"-"`
+	RawJSON     []byte
+	QueryID     string `json:"-"`
+}
+
+type DataSourceMetadataItem struct {
+	Timestamp string             `json:"timestamp"`
+	Result    DataSourceMetadata `json:"result"`
+}
+
+type DataSourceMetadata struct {
+	MaxIngestedEventTime time.Time `json:"maxIngestedEventTime"`
+}
+
+func (q *QueryDataSourceMetadata) setup()             { q.QueryType = DATASOURCEMETADATA }
+func (q *QueryDataSourceMetadata) GetRawJSON() []byte { return q.RawJSON }
+func (q *QueryDataSourceMetadata) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QueryDataSourceMetadata) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
+func (q *QueryDataSourceMetadata) onResponse(content []byte) error {
+	res := new([]DataSourceMetadataItem)
+	err := json.Unmarshal(content, res)
+	if err != nil {
+		return err
+	}
+	q.QueryResult = *res
+	q.RawJSON = content
+	return nil
+}
+
+// MaxIngestedEventTime is the timestamp of the latest ingested event, or the
+// zero time if the datasource has no data.
+func (q *QueryDataSourceMetadata) MaxIngestedEventTime() time.Time {
+	if len(q.QueryResult) == 0 {
+		return time.Time{}
+	}
+	return q.QueryResult[0].Result.MaxIngestedEventTime
+}
+
 // ---------------------------------
 // Timeseries Query
 // ---------------------------------
 
 type QueryTimeseries struct {
 	QueryType        QueryType              `json:"queryType"`
-	DataSource       string                 `json:"dataSource"`
-	Granularity      Granlarity             `json:"granularity"`
+	DataSource       DataSource             `json:"dataSource"`
+	Granularity      Granularity            `json:"granularity"`
 	Filter           *Filter                `json:"filter,omitempty"`
 	Aggregations     []Aggregation          `json:"aggregations"`
 	PostAggregations []PostAggregation      `json:"postAggregations,omitempty"`
 	Intervals        Intervals              `json:"intervals"`
+	VirtualColumns   []VirtualColumn        `json:"virtualColumns,omitempty"`
+	Descending       bool                   `json:"descending,omitempty"`
+	Limit            int                    `json:"limit,omitempty"`
 	Context          map[string]interface{} `json:"context,omitempty"`
 
+	// SkipEmptyBuckets goes in the context; it's not a field of the query.
+	SkipEmptyBuckets bool `json:"-"`
+
 	QueryResult []Timeseries `json:"-"`
 	RawJSON     []byte
+	QueryID     string `json:"-"`
 }
 
 type Timeseries struct {
@@ -209,8 +388,20 @@ type Timeseries struct {
 	Result    map[string]interface{} `json:"result"`
 }
 
-func (q *QueryTimeseries) setup()             { q.QueryType = TIMESERIES }
+func (q *QueryTimeseries) setup() { q.QueryType = TIMESERIES }
+
+// MarshalJSON adds skipEmptyBuckets to a copy of the Context, so that the
+// caller's map is never written to.
+func (q QueryTimeseries) MarshalJSON() ([]byte, error) {
+	if q.SkipEmptyBuckets {
+		withContextValue(&q.Context, "skipEmptyBuckets", true)
+	}
+	type alias QueryTimeseries
+	return json.Marshal(alias(q))
+}
 func (q *QueryTimeseries) GetRawJSON() []byte { return q.RawJSON }
+func (q *QueryTimeseries) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QueryTimeseries) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
 func (q *QueryTimeseries) onResponse(content []byte) error {
 	res := new([]Timeseries)
 	err := json.Unmarshal(content, res)
@@ -222,14 +413,109 @@ func (q *QueryTimeseries) onResponse(content []byte) error {
 	return nil
 }
 
+// FillEmptyBuckets adds to QueryResult a row for each bucket of the query's
+// intervals that has none, with every aggregation and post aggregation set to
+// value (typically 0 or nil), so that the series has no gaps whether or not
+// the query skipped empty buckets. Rows are then ordered as Descending says
+// and cut to Limit, and no more empty buckets than Limit are kept along the
+// way. The "none" and "all" granularities have no buckets to fill.
+func (q *QueryTimeseries) FillEmptyBuckets(value interface{}) error {
+	switch q.Granularity {
+	case nil, GranNone, GranAll:
+		return fmt.Errorf("godruid: can't fill empty buckets with granularity %v", q.Granularity)
+	}
+	intervals, err := ParseIntervals(q.Intervals)
+	if err != nil {
+		return err
+	}
+
+	type row struct {
+		t  time.Time
+		ts Timeseries
+	}
+	rows := make([]row, 0, len(q.QueryResult))
+	for _, ts := range q.QueryResult {
+		t, err := time.Parse(time.RFC3339Nano, ts.Timestamp)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row{t, ts})
+	}
+	sort.SliceStable(rows, func(a, b int) bool { return rows[a].t.Before(rows[b].t) })
+
+	var names []string
+	for _, agg := range q.Aggregations {
+		if agg.Type == "filtered" && agg.Aggregator != nil {
+			names = append(names, agg.Aggregator.Name)
+		} else {
+			names = append(names, agg.Name)
+		}
+	}
+	for _, pa := range q.PostAggregations {
+		names = append(names, pa.Name)
+	}
+
+	// Empty buckets come in ascending order: past Limit of them, the rest can
+	// only fall off the end of an ascending result, and the first ones off the
+	// end of a descending one.
+	var empty []row
+	var last time.Time // the last bucket looked at; one may span two intervals
+	next := 0
+buckets:
+	for n, i := range MergeIntervals(intervals) {
+		for b := q.Granularity.Bucket(i.Start); b.Before(i.End); b = q.Granularity.Next(b) {
+			if n > 0 && !b.After(last) {
+				continue
+			}
+			last = b
+			if q.Limit > 0 && len(empty) >= q.Limit {
+				if !q.Descending {
+					break buckets
+				}
+				if len(empty) >= 2*q.Limit {
+					empty = append(empty[:0], empty[len(empty)-q.Limit:]...)
+				}
+			}
+			end := q.Granularity.Next(b)
+			for next < len(rows) && rows[next].t.Before(b) {
+				next++
+			}
+			if next < len(rows) && rows[next].t.Before(end) {
+				continue
+			}
+			result := make(map[string]interface{}, len(names))
+			for _, name := range names {
+				result[name] = value
+			}
+			empty = append(empty, row{b, Timeseries{Timestamp: b.Format(druidTimeFormat), Result: result}})
+		}
+	}
+
+	filled := append(append([]row(nil), rows...), empty...)
+	sort.SliceStable(filled, func(a, b int) bool {
+		if q.Descending {
+			return filled[a].t.After(filled[b].t)
+		}
+		return filled[a].t.Before(filled[b].t)
+	})
+	if q.Limit > 0 && len(filled) > q.Limit {
+		filled = filled[:q.Limit]
+	}
+	q.QueryResult = make([]Timeseries, len(filled))
+	for i, r := range filled {
+		q.QueryResult[i] = r.ts
+	}
+	return nil
+}
+
 // ---------------------------------
 // TopN Query
 // ---------------------------------
 
 type QueryTopN struct {
 	QueryType        QueryType              `json:"queryType"`
-	DataSource       string                 `json:"dataSource"`
-	Granularity      Granlarity             `json:"granularity"`
+	DataSource       DataSource             `json:"dataSource"`
+	Granularity      Granularity            `json:"granularity"`
 	Dimension        DimSpec                `json:"dimension"`
 	Threshold        int                    `json:"threshold"`
 	Metric           interface{}            `json:"metric"` // *TopNMetric
@@ -237,10 +523,12 @@ type QueryTopN struct {
 	Aggregations     []Aggregation          `json:"aggregations"`
 	PostAggregations []PostAggregation      `json:"postAggregations,omitempty"`
 	Intervals        Intervals              `json:"intervals"`
+	VirtualColumns   []VirtualColumn        `json:"virtualColumns,omitempty"`
 	Context          map[string]interface{} `json:"context,omitempty"`
 
 	QueryResult []TopNItem `json:"-"`
 	RawJSON     []byte
+	QueryID     string `json:"-"`
 }
 
 type TopNItem struct {
@@ -250,6 +538,8 @@ type TopNItem struct {
 
 func (q *QueryTopN) setup()             { q.QueryType = TOPN }
 func (q *QueryTopN) GetRawJSON() []byte { return q.RawJSON }
+func (q *QueryTopN) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QueryTopN) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
 func (q *QueryTopN) onResponse(content []byte) error {
 	res := new([]TopNItem)
 	err := json.Unmarshal(content, res)
@@ -266,18 +556,21 @@ func (q *QueryTopN) onResponse(content []byte) error {
 // ---------------------------------
 
 type QuerySelect struct {
-	QueryType   QueryType              `json:"queryType"`
-	DataSource  string                 `json:"dataSource"`
-	Intervals   Intervals              `json:"intervals"`
-	Filter      *Filter                `json:"filter,omitempty"`
-	Dimensions  []DimSpec              `json:"dimensions"`
-	Metrics     []string               `json:"metrics"`
-	Granularity Granlarity             `json:"granularity"`
-	PagingSpec  map[string]interface{} `json:"pagingSpec,omitempty"`
-	Context     map[string]interface{} `json:"context,omitempty"`
+	QueryType      QueryType              `json:"queryType"`
+	DataSource     DataSource             `json:"dataSource"`
+	Intervals      Intervals              `json:"intervals"`
+	VirtualColumns []VirtualColumn        `json:"virtualColumns,omitempty"`
+	Filter         *Filter                `json:"filter,omitempty"`
+	Dimensions     []DimSpec              `json:"dimensions"`
+	Metrics        []string               `json:"metrics"`
+	Granularity    Granularity            `json:"granularity"`
+	Descending     bool                   `json:"descending,omitempty"`
+	PagingSpec     *PagingSpec            `json:"pagingSpec,omitempty"`
+	Context        map[string]interface{} `json:"context,omitempty"`
 
 	QueryResult SelectBlob `json:"-"`
 	RawJSON     []byte
+	QueryID     string `json:"-"`
 }
 
 // Select json blob from druid comes back as following:
@@ -290,8 +583,8 @@ type SelectBlob struct {
 }
 
 type SelectResult struct {
-	PagingIdentifiers map[string]interface{} `json:"pagingIdentifiers"`
-	Events            []SelectEvent          `json:"events"`
+	PagingIdentifiers map[string]int64 `json:"pagingIdentifiers"`
+	Events            []SelectEvent    `json:"events"`
 }
 
 type SelectEvent struct {
@@ -302,6 +595,8 @@ type SelectEvent struct {
 
 func (q *QuerySelect) setup()             { q.QueryType = SELECT }
 func (q *QuerySelect) GetRawJSON() []byte { return q.RawJSON }
+func (q *QuerySelect) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QuerySelect) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
 func (q *QuerySelect) onResponse(content []byte) error {
 	res := new([]SelectBlob)
 	err := json.Unmarshal(content, res)
@@ -322,28 +617,123 @@ func (q *QuerySelect) onResponse(content []byte) error {
 // ---------------------------------
 
 type QueryScan struct {
-	QueryType    QueryType              `json:"queryType"`
-	DataSource   string                 `json:"dataSource"`
-	Limit        int                    `json:"limit,omitempty"`
-	Columns      []string               `json:"columns,omitempty"`
-	ResultFormat string                 `json:"resultFormat,omitempty"`
-	Metric       interface{}            `json:"metric"` // *TopNMetric
-	Filter       *Filter                `json:"filter,omitempty"`
-	Intervals    Intervals              `json:"intervals"`
-	Context      map[string]interface{} `json:"context,omitempty"`
+	QueryType      QueryType              `json:"queryType"`
+	DataSource     DataSource             `json:"dataSource"`
+	Intervals      Intervals              `json:"intervals"`
+	VirtualColumns []VirtualColumn        `json:"virtualColumns,omitempty"`
+	Filter         *Filter                `json:"filter,omitempty"`
+	Columns        []string               `json:"columns,omitempty"`
+	ResultFormat   string                 `json:"resultFormat,omitempty"`
+	BatchSize      int                    `json:"batchSize,omitempty"`
+	Limit          int                    `json:"limit,omitempty"`
+	Offset         int                    `json:"offset,omitempty"`
+	Order          ScanOrder              `json:"order,omitempty"`
+	Legacy         *bool                  `json:"legacy,omitempty"`
+	Context        map[string]interface{} `json:"context,omitempty"`
 
 	QueryResult []ScanBlob `json:"-"`
 	RawJSON     []byte
+	QueryID     string `json:"-"`
 }
 
+const (
+	ScanResultFormatList          = "list"
+	ScanResultFormatCompactedList = "compactedList"
+)
+
+// ScanOrder orders rows by __time. Druid only orders scans whose limit is
+// small enough, see druid.query.scan.maxRowsQueuedForOrdering.
+type ScanOrder string
+
+const (
+	ScanOrderNone       ScanOrder = "none"
+	ScanOrderAscending  ScanOrder = "ascending"
+	ScanOrderDescending ScanOrder = "descending"
+)
+
+// ScanBlob is a batch of scan results. Rows holds its events whatever the
+// result format; Events is only filled for the "list" one.
 type ScanBlob struct {
 	SegmentID string                   `json:"segmentId"`
 	Columns   []string                 `json:"columns"`
 	Events    []map[string]interface{} `json:"events"`
+	Rows      []ScanRow                `json:"-"`
+}
+
+func (b *ScanBlob) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		SegmentID string        `json:"segmentId"`
+		Columns   []string      `json:"columns"`
+		Events    []interface{} `json:"events"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	b.SegmentID, b.Columns = raw.SegmentID, raw.Columns
+	b.Events, b.Rows = nil, make([]ScanRow, 0, len(raw.Events))
+	for _, e := range raw.Events {
+		if m, ok := e.(map[string]interface{}); ok {
+			b.Events = append(b.Events, m)
+		}
+		b.Rows = append(b.Rows, newScanRow(raw.SegmentID, raw.Columns, e))
+	}
+	return nil
+}
+
+// ScanRow is a single scan event, whatever the result format it came in.
+// Values are in the order of Columns.
+type ScanRow struct {
+	SegmentID string
+	Columns   []string
+	Values    []interface{}
+}
+
+// Get returns the value of the named column, or nil if there is no such column.
+func (r ScanRow) Get(column string) interface{} {
+	for i, c := range r.Columns {
+		if c == column && i < len(r.Values) {
+			return r.Values[i]
+		}
+	}
+	return nil
+}
+
+func (r ScanRow) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(r.Columns))
+	for i, c := range r.Columns {
+		if i < len(r.Values) {
+			m[c] = r.Values[i]
+		}
+	}
+	return m
+}
+
+// newScanRow turns an event of the "list" format (an object) or of the
+// "compactedList" format (an array in the order of columns) into a ScanRow.
+func newScanRow(segmentID string, columns []string, event interface{}) ScanRow {
+	row := ScanRow{SegmentID: segmentID, Columns: columns}
+	switch e := event.(type) {
+	case []interface{}:
+		row.Values = e
+	case map[string]interface{}:
+		if len(columns) == 0 {
+			for c := range e {
+				row.Columns = append(row.Columns, c)
+			}
+			sort.Strings(row.Columns)
+		}
+		row.Values = make([]interface{}, len(row.Columns))
+		for i, c := range row.Columns {
+			row.Values[i] = e[c]
+		}
+	}
+	return row
 }
 
 func (q *QueryScan) setup()             { q.QueryType = SCAN }
 func (q *QueryScan) GetRawJSON() []byte { return q.RawJSON }
+func (q *QueryScan) GetQueryID() string { return getQueryID(q.Context, q.QueryID) }
+func (q *QueryScan) setQueryID() func() { return withQueryID(&q.Context, &q.QueryID) }
 func (q *QueryScan) onResponse(content []byte) error {
 	res := new([]ScanBlob)
 	err := json.Unmarshal(content, res)
@@ -354,3 +744,12 @@ func (q *QueryScan) onResponse(content []byte) error {
 	q.RawJSON = content
 	return nil
 }
+
+// Rows returns the rows of all the blobs of QueryResult.
+func (q *QueryScan) Rows() []ScanRow {
+	var rows []ScanRow
+	for _, blob := range q.QueryResult {
+		rows = append(rows, blob.Rows...)
+	}
+	return rows
+}
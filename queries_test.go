@@ -0,0 +1,200 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTimeseries(t *testing.T) {
+	Convey("TestTimeseries", t, func() {
+		newQuery := func() *QueryTimeseries {
+			return &QueryTimeseries{
+				DataSource:       "wikipedia",
+				Granularity:      GranDay,
+				Intervals:        "2024-01-01/2024-01-05",
+				Aggregations:     []Aggregation{AggCount("rows")},
+				PostAggregations: []PostAggregation{PostAggExpression("double", "rows * 2")},
+				Limit:            3,
+				SkipEmptyBuckets: true,
+				QueryResult: []Timeseries{
+					{"2024-01-04T00:00:00.000Z", map[string]interface{}{"rows": 4.0, "double": 8.0}},
+					{"2024-01-02T00:00:00.000Z", map[string]interface{}{"rows": 2.0, "double": 4.0}},
+				},
+			}
+		}
+
+		Convey("Options", func() {
+			q := newQuery()
+			q.QueryResult = nil
+			q.Descending = true
+			shared := map[string]interface{}{"priority": 1}
+			q.Context = shared
+			q.setup()
+			data, err := json.Marshal(q)
+			So(err, ShouldBeNil)
+			var sent struct {
+				Descending bool
+				Limit      int
+				Context    map[string]interface{}
+			}
+			So(json.Unmarshal(data, &sent), ShouldBeNil)
+			So(sent.Descending, ShouldBeTrue)
+			So(sent.Limit, ShouldEqual, 3)
+			So(sent.Context["skipEmptyBuckets"], ShouldEqual, true)
+			So(sent.Context["priority"], ShouldEqual, 1.0)
+			So(shared, ShouldResemble, map[string]interface{}{"priority": 1})
+
+			q.SkipEmptyBuckets = false
+			sent.Context = nil
+			data, err = json.Marshal(q)
+			So(err, ShouldBeNil)
+			So(json.Unmarshal(data, &sent), ShouldBeNil)
+			So(sent.Context, ShouldResemble, map[string]interface{}{"priority": 1.0})
+		})
+
+		Convey("FillEmptyBuckets", func() {
+			q := newQuery()
+			q.Limit = 0
+			So(q.FillEmptyBuckets(0), ShouldBeNil)
+			So(q.QueryResult, ShouldResemble, []Timeseries{
+				{"2024-01-01T00:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+				{"2024-01-02T00:00:00.000Z", map[string]interface{}{"rows": 2.0, "double": 4.0}},
+				{"2024-01-03T00:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+				{"2024-01-04T00:00:00.000Z", map[string]interface{}{"rows": 4.0, "double": 8.0}},
+			})
+
+			q = newQuery()
+			q.Descending = true
+			So(q.FillEmptyBuckets(nil), ShouldBeNil)
+			So(q.QueryResult, ShouldResemble, []Timeseries{
+				{"2024-01-04T00:00:00.000Z", map[string]interface{}{"rows": 4.0, "double": 8.0}},
+				{"2024-01-03T00:00:00.000Z", map[string]interface{}{"rows": nil, "double": nil}},
+				{"2024-01-02T00:00:00.000Z", map[string]interface{}{"rows": 2.0, "double": 4.0}},
+			})
+
+			// A day spanning two intervals is a single bucket.
+			q = newQuery()
+			q.QueryResult = nil
+			q.Intervals = []string{"2024-01-01T00:00/2024-01-01T06:00", "2024-01-01T12:00/2024-01-02T06:00"}
+			So(q.FillEmptyBuckets(0), ShouldBeNil)
+			So(q.QueryResult, ShouldResemble, []Timeseries{
+				{"2024-01-01T00:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+				{"2024-01-02T00:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+			})
+		})
+
+		Convey("FillEmptyBuckets over a long interval", func() {
+			q := newQuery()
+			q.Granularity = GranHour
+			q.Intervals = "2014-01-01/2024-01-05"
+			q.Limit = 2
+			So(q.FillEmptyBuckets(0), ShouldBeNil)
+			So(q.QueryResult, ShouldResemble, []Timeseries{
+				{"2014-01-01T00:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+				{"2014-01-01T01:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+			})
+
+			q = newQuery()
+			q.Granularity = GranHour
+			q.Intervals = "2014-01-01/2024-01-05"
+			q.Descending = true
+			So(q.FillEmptyBuckets(0), ShouldBeNil)
+			So(q.QueryResult, ShouldResemble, []Timeseries{
+				{"2024-01-04T23:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+				{"2024-01-04T22:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+				{"2024-01-04T21:00:00.000Z", map[string]interface{}{"rows": 0, "double": 0}},
+			})
+
+			for _, gran := range []Granularity{nil, GranNone, GranAll} {
+				q.Granularity = gran
+				So(q.FillEmptyBuckets(0), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestScanBlob(t *testing.T) {
+	Convey("TestScanBlob", t, func() {
+		var blobs []ScanBlob
+		So(json.Unmarshal([]byte(`[
+			{"segmentId":"a","columns":["__time","page"],"events":[{"__time":1,"page":"Druid"}]},
+			{"segmentId":"b","columns":["__time","page"],"events":[[2,"Go"]]}
+		]`), &blobs), ShouldBeNil)
+		So(blobs[0].Events, ShouldResemble, []map[string]interface{}{{"__time": 1.0, "page": "Druid"}})
+		So(blobs[1].Events, ShouldBeNil)
+
+		q := &QueryScan{QueryResult: blobs}
+		So(q.Rows(), ShouldResemble, []ScanRow{
+			{"a", []string{"__time", "page"}, []interface{}{1.0, "Druid"}},
+			{"b", []string{"__time", "page"}, []interface{}{2.0, "Go"}},
+		})
+	})
+}
+
+func TestTimeBoundary(t *testing.T) {
+	Convey("TestTimeBoundary", t, func() {
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}))
+		defer server.Close()
+		client := &Client{Url: server.URL}
+		minTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		maxTime := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+
+		Convey("both bounds", func() {
+			body = `[{"timestamp":"2024-01-01T00:00:00.000Z","result":{"minTime":"2024-01-01T00:00:00.000Z","maxTime":"2024-01-31T23:59:59.000Z"}}]`
+			q := &QueryTimeBoundary{DataSource: "wikipedia"}
+			So(client.Query(q), ShouldBeNil)
+			So(q.QueryResult[0].Result.MinTime.Equal(minTime), ShouldBeTrue)
+			So(q.QueryResult[0].Result.MaxTime.Equal(maxTime), ShouldBeTrue)
+
+			i, ok := q.Interval()
+			So(ok, ShouldBeTrue)
+			So(i.Start.Equal(minTime), ShouldBeTrue)
+			So(i.End.Equal(maxTime.Add(time.Millisecond)), ShouldBeTrue)
+
+			i, err := client.DataSourceInterval(context.Background(), "wikipedia")
+			So(err, ShouldBeNil)
+			So(i.End.Equal(maxTime.Add(time.Millisecond)), ShouldBeTrue)
+		})
+
+		Convey("one bound", func() {
+			body = `[{"timestamp":"2024-01-31T23:59:59.000Z","result":{"maxTime":"2024-01-31T23:59:59.000Z"}}]`
+			q := &QueryTimeBoundary{DataSource: "wikipedia", Bound: BoundMaxTime}
+			So(client.Query(q), ShouldBeNil)
+			So(q.QueryResult[0].Result.MinTime.IsZero(), ShouldBeTrue)
+			So(q.QueryResult[0].Result.MaxTime.Equal(maxTime), ShouldBeTrue)
+			_, ok := q.Interval()
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("no data", func() {
+			body = `[]`
+			_, err := client.DataSourceInterval(context.Background(), "wikipedia")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestDataSourceMetadata(t *testing.T) {
+	Convey("TestDataSourceMetadata", t, func() {
+		q := &QueryDataSourceMetadata{DataSource: "wikipedia"}
+		So(q.MaxIngestedEventTime().IsZero(), ShouldBeTrue)
+
+		So(q.onResponse([]byte(`[{"timestamp":"2024-01-31T00:00:00.000Z","result":{"maxIngestedEventTime":"2024-01-31T12:34:56.789Z"}}]`)), ShouldBeNil)
+		So(q.MaxIngestedEventTime().Equal(time.Date(2024, 1, 31, 12, 34, 56, 789e6, time.UTC)), ShouldBeTrue)
+
+		q.setup()
+		data, err := json.Marshal(q)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, `"queryType":"dataSourceMetadata"`)
+	})
+}
@@ -35,6 +35,30 @@ func LimitDefault(limit int, columns ...[]Column) *Limit {
 	}
 }
 
+// ---------------------------------
+// PagingSpec
+// ---------------------------------
+
+// PagingSpec tells a select query where to start and how many events to
+// return. PagingIdentifiers are the offsets, by segment, of the last events
+// read, as returned by the previous page. With FromNext false, they must be
+// those offsets plus one (minus one when descending) instead.
+type PagingSpec struct {
+	PagingIdentifiers map[string]int64 `json:"pagingIdentifiers"`
+	Threshold         int              `json:"threshold"`
+	FromNext          *bool            `json:"fromNext,omitempty"`
+}
+
+func NewPagingSpec(threshold int, pagingIdentifiers map[string]int64) *PagingSpec {
+	if pagingIdentifiers == nil {
+		pagingIdentifiers = map[string]int64{}
+	}
+	return &PagingSpec{
+		PagingIdentifiers: pagingIdentifiers,
+		Threshold:         threshold,
+	}
+}
+
 // ---------------------------------
 // SearchQuerySpec
 // ---------------------------------
@@ -0,0 +1,309 @@
+package godruid
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Check http://druid.io/docs/latest/querying/sql.html for detail description.
+
+type SQLResultFormat string
+
+const (
+	SQLResultObject      SQLResultFormat = "object"
+	SQLResultArray       SQLResultFormat = "array"
+	SQLResultObjectLines SQLResultFormat = "objectLines"
+	SQLResultArrayLines  SQLResultFormat = "arrayLines"
+	SQLResultCSV         SQLResultFormat = "csv"
+)
+
+// ---------------------------------
+// SQL Query
+// ---------------------------------
+
+// QuerySQL is sent to Client.SQLEndPoint instead of the native query end point.
+//
+// Whatever the result format, each row ends up in QueryResult with its values
+// in the order of Columns. Column names are always known for the object
+// formats; for the array and csv formats they are only known with Header on.
+// Column types are filled in when TypesHeader or SQLTypesHeader is on as well.
+type QuerySQL struct {
+	Query          string                 `json:"query"`
+	ResultFormat   SQLResultFormat        `json:"resultFormat,omitempty"`
+	Header         bool                   `json:"header,omitempty"`
+	TypesHeader    bool                   `json:"typesHeader,omitempty"`
+	SQLTypesHeader bool                   `json:"sqlTypesHeader,omitempty"`
+	Parameters     []SQLParameter         `json:"parameters,omitempty"`
+	Context        map[string]interface{} `json:"context,omitempty"`
+
+	Columns     []SQLColumn     `json:"-"`
+	QueryResult [][]interface{} `json:"-"`
+	RawJSON     []byte
+	QueryID     string `json:"-"`
+}
+
+type SQLColumn struct {
+	Name    string
+	Type    string
+	SQLType string
+}
+
+// SQL queries are cancelled by their sqlQueryId rather than their queryId.
+func sqlQueryID(context map[string]interface{}) string {
+	id, _ := context["sqlQueryId"].(string)
+	return id
+}
+
+func (q *QuerySQL) setup()             {}
+func (q *QuerySQL) GetRawJSON() []byte { return q.RawJSON }
+func (q *QuerySQL) GetQueryID() string { return contextID(q.Context, "sqlQueryId", q.QueryID) }
+func (q *QuerySQL) setQueryID() func() {
+	return withContextID(&q.Context, "sqlQueryId", &q.QueryID)
+}
+func (q *QuerySQL) onResponse(content []byte) error {
+	q.Columns = nil
+	q.QueryResult = nil
+	var err error
+	switch q.ResultFormat {
+	case SQLResultObject, SQLResultObjectLines, "":
+		err = q.decodeObjects(content)
+	case SQLResultArray, SQLResultArrayLines:
+		err = q.decodeArrays(content)
+	case SQLResultCSV:
+		err = q.decodeCSV(content)
+	default:
+		err = fmt.Errorf("godruid: unknown SQL result format %q", q.ResultFormat)
+	}
+	if err != nil {
+		return err
+	}
+	q.RawJSON = content
+	return nil
+}
+
+// Rows returns QueryResult as maps keyed by column name.
+// It returns nil when the column names are unknown.
+func (q *QuerySQL) Rows() []map[string]interface{} {
+	if len(q.Columns) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, len(q.QueryResult))
+	for i, values := range q.QueryResult {
+		row := make(map[string]interface{}, len(q.Columns))
+		for j, col := range q.Columns {
+			if j < len(values) {
+				row[col.Name] = values[j]
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// decodeObjects handles "object" (a JSON array of objects) and "objectLines"
+// (one object per line). Keys are read in order so that Columns matches the
+// SELECT list even without a header.
+func (q *QuerySQL) decodeObjects(content []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	inArray := q.ResultFormat != SQLResultObjectLines
+	if inArray {
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+	}
+	index := make(map[string]int)
+	first := true
+	for dec.More() {
+		keys, values, err := readObject(dec)
+		if err != nil {
+			return err
+		}
+		if first {
+			first = false
+			for i, key := range keys {
+				index[key] = i
+				col := SQLColumn{Name: key}
+				if q.Header {
+					if types, ok := values[i].(map[string]interface{}); ok {
+						col.Type, _ = types["type"].(string)
+						col.SQLType, _ = types["sqlType"].(string)
+					}
+				}
+				q.Columns = append(q.Columns, col)
+			}
+			if q.Header {
+				continue
+			}
+		}
+		row := make([]interface{}, len(q.Columns))
+		for i, key := range keys {
+			if j, ok := index[key]; ok {
+				row[j] = values[i]
+			}
+		}
+		q.QueryResult = append(q.QueryResult, row)
+	}
+	if inArray {
+		return expectDelim(dec, ']')
+	}
+	return nil
+}
+
+// decodeArrays handles "array" (a JSON array of arrays) and "arrayLines"
+// (one array per line).
+func (q *QuerySQL) decodeArrays(content []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	inArray := q.ResultFormat != SQLResultArrayLines
+	if inArray {
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+	}
+	var rows [][]interface{}
+	for dec.More() {
+		var row []interface{}
+		if err := dec.Decode(&row); err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+	if inArray {
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	q.QueryResult = q.takeHeaderRows(rows)
+	return nil
+}
+
+func (q *QuerySQL) decodeCSV(content []byte) error {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	var rows [][]interface{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		row := make([]interface{}, len(record))
+		for i, v := range record {
+			row[i] = v
+		}
+		rows = append(rows, row)
+	}
+	q.QueryResult = q.takeHeaderRows(rows)
+	return nil
+}
+
+// takeHeaderRows fills Columns from the leading header rows of the array and
+// csv formats: names first, then types and SQL types if they were asked for.
+func (q *QuerySQL) takeHeaderRows(rows [][]interface{}) [][]interface{} {
+	if !q.Header || len(rows) == 0 {
+		return rows
+	}
+	for _, name := range rows[0] {
+		q.Columns = append(q.Columns, SQLColumn{Name: fmt.Sprint(name)})
+	}
+	rows = rows[1:]
+	if q.TypesHeader && len(rows) > 0 {
+		for i, t := range rows[0] {
+			if i < len(q.Columns) {
+				q.Columns[i].Type = fmt.Sprint(t)
+			}
+		}
+		rows = rows[1:]
+	}
+	if q.SQLTypesHeader && len(rows) > 0 {
+		for i, t := range rows[0] {
+			if i < len(q.Columns) {
+				q.Columns[i].SQLType = fmt.Sprint(t)
+			}
+		}
+		rows = rows[1:]
+	}
+	return rows
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("godruid: expected %v, got %v", delim, t)
+	}
+	return nil
+}
+
+func readObject(dec *json.Decoder) (keys []string, values []interface{}, err error) {
+	if err = expectDelim(dec, '{'); err != nil {
+		return
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var v interface{}
+		if err = dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, t.(string))
+		values = append(values, v)
+	}
+	err = expectDelim(dec, '}')
+	return
+}
+
+// ---------------------------------
+// SQL Dynamic Parameters
+// ---------------------------------
+
+// SQLParameter fills a "?" placeholder of QuerySQL.Query.
+type SQLParameter struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+const sqlTimestampFormat = "2006-01-02 15:04:05.000"
+
+func SQLParamVarchar(value string) SQLParameter {
+	return SQLParameter{Type: "VARCHAR", Value: value}
+}
+
+func SQLParamInteger(value int) SQLParameter {
+	return SQLParameter{Type: "INTEGER", Value: value}
+}
+
+func SQLParamBigint(value int64) SQLParameter {
+	return SQLParameter{Type: "BIGINT", Value: value}
+}
+
+func SQLParamFloat(value float32) SQLParameter {
+	return SQLParameter{Type: "FLOAT", Value: value}
+}
+
+func SQLParamDouble(value float64) SQLParameter {
+	return SQLParameter{Type: "DOUBLE", Value: value}
+}
+
+func SQLParamBoolean(value bool) SQLParameter {
+	return SQLParameter{Type: "BOOLEAN", Value: value}
+}
+
+// SQLParamTimestamp sends t in UTC, which is how Druid reads TIMESTAMP literals
+// unless sqlTimeZone is set in the context.
+func SQLParamTimestamp(t time.Time) SQLParameter {
+	return SQLParameter{Type: "TIMESTAMP", Value: t.UTC().Format(sqlTimestampFormat)}
+}
+
+func SQLParamDate(t time.Time) SQLParameter {
+	return SQLParameter{Type: "DATE", Value: t.UTC().Format("2006-01-02")}
+}
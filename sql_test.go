@@ -0,0 +1,59 @@
+package godruid
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSQLResultFormats(t *testing.T) {
+	Convey("TestSQLResultFormats", t, func() {
+		expected := [][]interface{}{{"US", float64(10)}, {"CA", float64(3)}}
+
+		Convey("object", func() {
+			query := &QuerySQL{ResultFormat: SQLResultObject, Header: true, TypesHeader: true, SQLTypesHeader: true}
+			err := query.onResponse([]byte(`[
+				{"country": {"type": "STRING", "sqlType": "VARCHAR"}, "cnt": {"type": "LONG", "sqlType": "BIGINT"}},
+				{"country": "US", "cnt": 10},
+				{"cnt": 3, "country": "CA"}
+			]`))
+			So(err, ShouldBeNil)
+			So(query.Columns, ShouldResemble, []SQLColumn{{"country", "STRING", "VARCHAR"}, {"cnt", "LONG", "BIGINT"}})
+			So(query.QueryResult, ShouldResemble, expected)
+			So(query.Rows()[1]["country"], ShouldEqual, "CA")
+		})
+
+		Convey("objectLines without header", func() {
+			query := &QuerySQL{ResultFormat: SQLResultObjectLines}
+			err := query.onResponse([]byte("{\"country\":\"US\",\"cnt\":10}\n{\"country\":\"CA\",\"cnt\":3}\n\n"))
+			So(err, ShouldBeNil)
+			So(query.Columns, ShouldResemble, []SQLColumn{{Name: "country"}, {Name: "cnt"}})
+			So(query.QueryResult, ShouldResemble, expected)
+		})
+
+		Convey("array", func() {
+			query := &QuerySQL{ResultFormat: SQLResultArray, Header: true, SQLTypesHeader: true}
+			err := query.onResponse([]byte(`[["country","cnt"],["VARCHAR","BIGINT"],["US",10],["CA",3]]`))
+			So(err, ShouldBeNil)
+			So(query.Columns, ShouldResemble, []SQLColumn{{Name: "country", SQLType: "VARCHAR"}, {Name: "cnt", SQLType: "BIGINT"}})
+			So(query.QueryResult, ShouldResemble, expected)
+		})
+
+		Convey("arrayLines", func() {
+			query := &QuerySQL{ResultFormat: SQLResultArrayLines}
+			err := query.onResponse([]byte("[\"US\",10]\n[\"CA\",3]\n\n"))
+			So(err, ShouldBeNil)
+			So(query.Columns, ShouldBeEmpty)
+			So(query.Rows(), ShouldBeNil)
+			So(query.QueryResult, ShouldResemble, expected)
+		})
+
+		Convey("csv", func() {
+			query := &QuerySQL{ResultFormat: SQLResultCSV, Header: true, TypesHeader: true}
+			err := query.onResponse([]byte("country,cnt\nSTRING,LONG\nUS,10\nCA,3\n\n"))
+			So(err, ShouldBeNil)
+			So(query.Columns, ShouldResemble, []SQLColumn{{Name: "country", Type: "STRING"}, {Name: "cnt", Type: "LONG"}})
+			So(query.QueryResult, ShouldResemble, [][]interface{}{{"US", "10"}, {"CA", "3"}})
+		})
+	})
+}
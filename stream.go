@@ -0,0 +1,196 @@
+package godruid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ---------------------------------
+// Streaming Scan
+// ---------------------------------
+
+// ScanRows reads the result of a scan query off the wire one event at a time,
+// so memory use does not grow with the size of the result. Both the "list" and
+// the "compactedList" result formats are understood.
+//
+//	rows, err := client.StreamScan(ctx, query)
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//		row := rows.Row()
+//		...
+//	}
+//	return rows.Err()
+type ScanRows struct {
+	body    io.ReadCloser
+	dec     *json.Decoder
+	cancel  func()
+	release func(error)
+
+	started  bool
+	inBlob   bool
+	inEvents bool
+	done     bool
+
+	segmentID string
+	columns   []string
+	row       ScanRow
+	err       error
+}
+
+// StreamScan runs query and returns an iterator over its rows.
+// The caller must Close the returned ScanRows.
+func (c *Client) StreamScan(ctx context.Context, query *QueryScan) (*ScanRows, error) {
+	reqJson, err := c.marshalQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if c.EndPoint == "" {
+		c.EndPoint = DefaultEndPoint
+	}
+
+	var body io.ReadCloser
+	url, release, err := c.send(ctx, c.EndPoint, reqJson, func(resp *http.Response) error {
+		body = resp.Body
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endPoint, queryID := c.EndPoint, rawQueryID(reqJson)
+	return &ScanRows{
+		body: body,
+		dec:  json.NewDecoder(body),
+		cancel: func() {
+			if ctx.Err() != nil {
+				c.cancelQuery(url, endPoint, queryID)
+			}
+		},
+		release: release,
+	}, nil
+}
+
+// StreamScanFunc runs query and calls fn for every row as it is decoded.
+// It stops at the first error returned by fn.
+func (c *Client) StreamScanFunc(ctx context.Context, query *QueryScan, fn func(row ScanRow) error) error {
+	rows, err := c.StreamScan(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows.Row()); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Next decodes the next row. It returns false at the end of the result or on error.
+func (r *ScanRows) Next() bool {
+	if r.done {
+		return false
+	}
+	ok, err := r.next()
+	if err != nil {
+		r.err = err
+		r.cancel()
+	}
+	if !ok {
+		r.done = true
+	}
+	return ok
+}
+
+func (r *ScanRows) Row() ScanRow {
+	return r.row
+}
+
+func (r *ScanRows) Err() error {
+	return r.err
+}
+
+// Close releases the connection. Closing before the end of the result
+// abandons the rest of it. Until then, the query counts as outstanding on the
+// broker of a BrokerPool.
+func (r *ScanRows) Close() error {
+	r.done = true
+	r.release(r.err)
+	return r.body.Close()
+}
+
+// next walks [{"segmentId": ..., "columns": [...], "events": [...]}, ...]
+// token by token, stopping on each element of an events array.
+func (r *ScanRows) next() (bool, error) {
+	if !r.started {
+		r.started = true
+		if err := expectDelim(r.dec, '['); err != nil {
+			return false, err
+		}
+	}
+	for {
+		switch {
+		case r.inEvents:
+			if r.dec.More() {
+				var event interface{}
+				if err := r.dec.Decode(&event); err != nil {
+					return false, err
+				}
+				r.row = newScanRow(r.segmentID, r.columns, event)
+				return true, nil
+			}
+			if err := expectDelim(r.dec, ']'); err != nil {
+				return false, err
+			}
+			r.inEvents = false
+
+		case r.inBlob:
+			if !r.dec.More() {
+				if err := expectDelim(r.dec, '}'); err != nil {
+					return false, err
+				}
+				r.inBlob = false
+				continue
+			}
+			t, err := r.dec.Token()
+			if err != nil {
+				return false, err
+			}
+			var v interface{}
+			switch t {
+			case "segmentId":
+				v = &r.segmentID
+			case "columns":
+				v = &r.columns
+			case "events":
+				if err := expectDelim(r.dec, '['); err != nil {
+					return false, err
+				}
+				r.inEvents = true
+				continue
+			default:
+				v = new(json.RawMessage)
+			}
+			if err := r.dec.Decode(v); err != nil {
+				return false, fmt.Errorf("godruid: decoding scan %v: %v", t, err)
+			}
+
+		default:
+			if !r.dec.More() {
+				return false, expectDelim(r.dec, ']')
+			}
+			if err := expectDelim(r.dec, '{'); err != nil {
+				return false, err
+			}
+			r.inBlob = true
+			r.segmentID = ""
+			r.columns = nil
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package godruid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStreamScan(t *testing.T) {
+	Convey("TestStreamScan", t, func() {
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}))
+		defer server.Close()
+
+		pool := NewBrokerPool([]string{server.URL}, LeastOutstanding)
+		client := &Client{Brokers: pool}
+		query := &QueryScan{DataSource: "wikipedia", Intervals: "2024-01-01/2024-01-02"}
+		read := func() ([]ScanRow, error) {
+			var rows []ScanRow
+			err := client.StreamScanFunc(context.Background(), query, func(row ScanRow) error {
+				rows = append(rows, row)
+				return nil
+			})
+			return rows, err
+		}
+
+		Convey("list", func() {
+			body = `[{"segmentId":"a","columns":["__time","page"],"events":[{"__time":1,"page":"Druid"},{"__time":2,"page":"Go"}],"rowSignature":[]},
+				{"segmentId":"b","columns":["__time","page"],"events":[{"__time":3,"page":"Scan"}]}]`
+			rows, err := read()
+			So(err, ShouldBeNil)
+			So(rows, ShouldResemble, []ScanRow{
+				{"a", []string{"__time", "page"}, []interface{}{1.0, "Druid"}},
+				{"a", []string{"__time", "page"}, []interface{}{2.0, "Go"}},
+				{"b", []string{"__time", "page"}, []interface{}{3.0, "Scan"}},
+			})
+		})
+
+		Convey("compactedList", func() {
+			body = `[{"segmentId":"a","columns":["__time","page"],"events":[[1,"Druid"],[2,null]]}]`
+			rows, err := read()
+			So(err, ShouldBeNil)
+			So(rows, ShouldResemble, []ScanRow{
+				{"a", []string{"__time", "page"}, []interface{}{1.0, "Druid"}},
+				{"a", []string{"__time", "page"}, []interface{}{2.0, nil}},
+			})
+		})
+
+		Convey("empty result", func() {
+			body = `[]`
+			rows, err := read()
+			So(err, ShouldBeNil)
+			So(rows, ShouldBeEmpty)
+		})
+
+		Convey("body cut off", func() {
+			body = `[{"segmentId":"a","columns":["page"],"events":[["Druid"],["G`
+			rows, err := read()
+			So(err, ShouldNotBeNil)
+			So(len(rows), ShouldEqual, 1)
+		})
+
+		Convey("outstanding until closed", func() {
+			body = `[]`
+			rows, err := client.StreamScan(context.Background(), query)
+			So(err, ShouldBeNil)
+			So(pool.brokers[0].outstanding, ShouldEqual, 1)
+			rows.Close()
+			rows.Close()
+			So(pool.brokers[0].outstanding, ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,161 @@
+package godruid
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The time column is always there, whatever the datasource.
+const TimeColumn = "__time"
+
+// ValidateColumns checks that the dimensions, aggregations and filters of query
+// only refer to columns among the given ones (as returned by a segmentMetadata
+// query, for instance) or to virtual columns of the query itself. Druid
+// expressions, in filters, aggregations and virtual columns, are checked too.
+//
+// Not checked: JavaScript functions, the fold and combine expressions of the
+// expression aggregator, extraction functions and lookups, post aggregations,
+// havings, and the inner query of a query datasource.
+// Queries without any column references (timeBoundary, segmentMetadata and
+// dataSourceMetadata) always pass; other query types, such as SQL, get an error.
+func ValidateColumns(query Query, columns []string) error {
+	known := map[string]bool{TimeColumn: true}
+	for _, c := range columns {
+		known[c] = true
+	}
+
+	refs := &columnRefs{}
+	var virtualColumns []VirtualColumn
+	switch q := query.(type) {
+	case *QueryGroupBy:
+		virtualColumns = q.VirtualColumns
+		refs.dimSpecs(q.Dimensions...)
+		refs.filter(q.Filter)
+		refs.aggregations(q.Aggregations)
+	case *QueryTimeseries:
+		virtualColumns = q.VirtualColumns
+		refs.filter(q.Filter)
+		refs.aggregations(q.Aggregations)
+	case *QueryTopN:
+		virtualColumns = q.VirtualColumns
+		refs.dimSpecs(q.Dimension)
+		refs.filter(q.Filter)
+		refs.aggregations(q.Aggregations)
+	case *QuerySearch:
+		virtualColumns = q.VirtualColumns
+		refs.add(q.SearchDimensions...)
+		refs.filter(q.Filter)
+	case *QuerySelect:
+		virtualColumns = q.VirtualColumns
+		refs.dimSpecs(q.Dimensions...)
+		refs.add(q.Metrics...)
+		refs.filter(q.Filter)
+	case *QueryScan:
+		virtualColumns = q.VirtualColumns
+		refs.add(q.Columns...)
+		refs.filter(q.Filter)
+	case *QueryTimeBoundary, *QuerySegmentMetadata, *QueryDataSourceMetadata:
+		return nil
+	default:
+		return fmt.Errorf("godruid: can't validate the columns of %T", query)
+	}
+	for _, vc := range virtualColumns {
+		known[vc.Name] = true
+		refs.expression(vc.Expression)
+	}
+
+	var missing []string
+	seen := make(map[string]bool)
+	for _, name := range refs.names {
+		if !known[name] && !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("godruid: unknown columns: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+type columnRefs struct {
+	names []string
+}
+
+func (r *columnRefs) add(names ...string) {
+	for _, name := range names {
+		if name != "" {
+			r.names = append(r.names, name)
+		}
+	}
+}
+
+func (r *columnRefs) expression(expr string) {
+	r.add(expressionIdentifiers(expr)...)
+}
+
+func (r *columnRefs) dimSpecs(dims ...DimSpec) {
+	for _, dim := range dims {
+		switch d := dim.(type) {
+		case string:
+			r.add(d)
+		case Dimension:
+			r.dimSpecs(&d)
+		case TimeExtractionDimensionSpec:
+			r.dimSpecs(&d)
+		case LookupDimension:
+			r.dimSpecs(&d)
+		case ListFilteredDimension:
+			r.dimSpecs(d.Delegate)
+		case RegexFilteredDimension:
+			r.dimSpecs(d.Delegate)
+		case PrefixFilteredDimension:
+			r.dimSpecs(d.Delegate)
+		case *Dimension:
+			r.add(d.Dimension)
+		case *TimeExtractionDimensionSpec:
+			r.add(d.Dimension)
+		case *LookupDimension:
+			r.add(d.Dimension)
+		case *ListFilteredDimension:
+			r.dimSpecs(d.Delegate)
+		case *RegexFilteredDimension:
+			r.dimSpecs(d.Delegate)
+		case *PrefixFilteredDimension:
+			r.dimSpecs(d.Delegate)
+		}
+	}
+}
+
+func (r *columnRefs) filter(f *Filter) {
+	if f == nil {
+		return
+	}
+	r.add(f.Dimension)
+	r.dimSpecs(f.Dimensions...)
+	r.filter(f.Field)
+	for _, field := range f.Fields {
+		r.filter(field)
+	}
+	r.expression(f.Expression)
+}
+
+func (r *columnRefs) aggregations(aggs []Aggregation) {
+	for i := range aggs {
+		r.aggregation(&aggs[i])
+	}
+}
+
+func (r *columnRefs) aggregation(agg *Aggregation) {
+	if agg == nil {
+		return
+	}
+	r.add(agg.FieldName)
+	r.add(agg.FieldNames...)
+	r.add(agg.Fields...)
+	r.expression(agg.Expression)
+	r.filter(agg.Filter)
+	r.aggregation(agg.Aggregator)
+}
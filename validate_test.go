@@ -0,0 +1,54 @@
+package godruid
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidateColumns(t *testing.T) {
+	Convey("TestValidateColumns", t, func() {
+		columns := []string{"page", "country", "price", "quantity"}
+		query := &QueryGroupBy{
+			DataSource:   TableName("wikipedia"),
+			Granularity:  GranAll,
+			Dimensions:   []DimSpec{"page", &Dimension{Dimension: "country", OutputName: "c"}},
+			Filter:       FilterSelector("country", "US"),
+			Aggregations: []Aggregation{AggCount("rows"), AggLongSum("added", "price")},
+			Intervals:    "2024-01-01/2024-01-02",
+		}
+
+		Convey("known columns", func() {
+			So(ValidateColumns(query, columns), ShouldBeNil)
+		})
+
+		Convey("missing columns", func() {
+			query.Dimensions = append(query.Dimensions, "city", Dimension{Type: "default", Dimension: "region"})
+			query.Filter = FilterAnd(query.Filter, FilterSelector("user", "bot"))
+			So(ValidateColumns(query, columns).Error(), ShouldEqual, "godruid: unknown columns: city, region, user")
+		})
+
+		Convey("virtual column references", func() {
+			query.VirtualColumns = []VirtualColumn{VirtualColumnExpression("revenue", `price * "quantity"`, OutputTypeDouble)}
+			query.Aggregations = append(query.Aggregations, AggDoubleSum("revenue", "revenue"))
+			So(ValidateColumns(query, columns), ShouldBeNil)
+
+			query.VirtualColumns[0].Expression = "price * discount"
+			So(ValidateColumns(query, columns).Error(), ShouldEqual, "godruid: unknown columns: discount")
+		})
+
+		Convey("expressions", func() {
+			query.Filter = FilterExpression("concat(page, 'suffix') == city")
+			query.Aggregations = append(query.Aggregations, AggDoubleSum("revenue", "").WithExpression("price * tax"))
+			So(ValidateColumns(query, columns).Error(), ShouldEqual, "godruid: unknown columns: city, tax")
+		})
+
+		Convey("queries without columns", func() {
+			So(ValidateColumns(&QueryTimeBoundary{DataSource: TableName("wikipedia")}, nil), ShouldBeNil)
+		})
+
+		Convey("unsupported queries", func() {
+			So(ValidateColumns(&QuerySQL{Query: "SELECT city FROM wikipedia"}, columns), ShouldNotBeNil)
+		})
+	})
+}
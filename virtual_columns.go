@@ -0,0 +1,29 @@
+package godruid
+
+type OutputType string
+
+const (
+	OutputTypeString OutputType = "STRING"
+	OutputTypeLong   OutputType = "LONG"
+	OutputTypeFloat  OutputType = "FLOAT"
+	OutputTypeDouble OutputType = "DOUBLE"
+)
+
+// VirtualColumn is a column computed at query time, which dimensions,
+// aggregations and filters of the query can use like any other column.
+// Check http://druid.io/docs/latest/querying/virtual-columns.html for detail description.
+type VirtualColumn struct {
+	Type       string     `json:"type"`
+	Name       string     `json:"name"`
+	Expression string     `json:"expression"`
+	OutputType OutputType `json:"outputType,omitempty"`
+}
+
+func VirtualColumnExpression(name, expression string, outputType OutputType) VirtualColumn {
+	return VirtualColumn{
+		Type:       "expression",
+		Name:       name,
+		Expression: expression,
+		OutputType: outputType,
+	}
+}